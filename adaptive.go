@@ -0,0 +1,254 @@
+package gottle
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	defaultAdditiveIncrease       = 1
+	defaultMultiplicativeDecrease = 0.5
+	defaultTargetLatency          = time.Second
+	defaultMaxErrorRate           = 0.05
+	defaultAdjustEvery            = time.Second * 10
+	defaultMinSamples             = 10
+)
+
+//AdaptiveOption configures an Adaptive limiter
+type AdaptiveOption func(*Adaptive)
+
+//AdditiveIncrease is an AdaptiveOption that sets how much the limit is
+//raised by after a healthy window. It defaults to 1, as well as below it
+func AdditiveIncrease(n int) AdaptiveOption {
+	return func(a *Adaptive) {
+		a.increase = n
+	}
+}
+
+//MultiplicativeDecrease is an AdaptiveOption that sets the factor, between
+//0 and 1, the limit is multiplied by after an unhealthy window. It defaults
+//to 0.5, which factors outside of that are replaced with
+func MultiplicativeDecrease(factor float64) AdaptiveOption {
+	return func(a *Adaptive) {
+		a.decrease = factor
+	}
+}
+
+//TargetLatency is an AdaptiveOption that sets the average latency of the
+//handler above which a window is unhealthy. It defaults to a second
+func TargetLatency(d time.Duration) AdaptiveOption {
+	return func(a *Adaptive) {
+		a.latency = d
+	}
+}
+
+//MaxErrorRate is an AdaptiveOption that sets the share of failed requests,
+//between 0 and 1, above which a window is unhealthy. Rates outside of
+//that are brought back within it. It defaults to 0.05
+func MaxErrorRate(rate float64) AdaptiveOption {
+	return func(a *Adaptive) {
+		a.errorRate = rate
+	}
+}
+
+//AdjustEvery is an AdaptiveOption that sets how long the windows the
+//limit is adjusted after are. It defaults to 10 seconds
+func AdjustEvery(d time.Duration) AdaptiveOption {
+	return func(a *Adaptive) {
+		a.every = d
+	}
+}
+
+//MinSamples is an AdaptiveOption that sets how many requests a window needs
+//before the limit is adjusted, so a single slow request can't cut it.
+//Windows with less are carried over. It defaults to 10
+func MinSamples(n int) AdaptiveOption {
+	return func(a *Adaptive) {
+		a.minSamples = n
+	}
+}
+
+//Adaptive adjusts the limit of a throttler to how the service it protects
+//is doing, rather than keeping it at a fixed value that is wrong half the time.
+//
+//Every window, the limit is raised additively if the average latency and
+//error rate of the requests let through were healthy, and cut multiplicatively
+//if they were not (AIMD), staying within the bounds it was created with.
+//Its Middleware measures the requests itself, other callers report them with Observe
+type Adaptive struct {
+	t *OnecacheThrottler
+
+	min, max   int
+	increase   int
+	decrease   float64
+	latency    time.Duration
+	errorRate  float64
+	every      time.Duration
+	minSamples int
+
+	mu       sync.Mutex
+	started  time.Time
+	requests int
+	failures int
+	elapsed  time.Duration
+}
+
+//NewAdaptive returns an Adaptive that adjusts the limit of t between min and max,
+//starting from the one t was created with. The throttler's interval is left as is
+func NewAdaptive(t *OnecacheThrottler, min, max int, opts ...AdaptiveOption) *Adaptive {
+	a := &Adaptive{
+		t:          t,
+		min:        min,
+		max:        max,
+		increase:   defaultAdditiveIncrease,
+		decrease:   defaultMultiplicativeDecrease,
+		latency:    defaultTargetLatency,
+		errorRate:  defaultMaxErrorRate,
+		every:      defaultAdjustEvery,
+		minSamples: defaultMinSamples,
+		started:    t.now(),
+	}
+
+	for _, opt := range opts {
+		opt(a)
+	}
+
+	if a.min < 1 {
+		a.min = 1
+	}
+
+	if a.max < a.min {
+		a.max = a.min
+	}
+
+	//Factors outside of it would never cut the limit, or always cut it to min
+	if a.decrease <= 0 || a.decrease >= 1 {
+		a.decrease = defaultMultiplicativeDecrease
+	}
+
+	if a.increase < 1 {
+		a.increase = defaultAdditiveIncrease
+	}
+
+	if a.errorRate < 0 {
+		a.errorRate = 0
+	}
+
+	if a.errorRate > 1 {
+		a.errorRate = 1
+	}
+
+	limit, interval := t.throttleCondition()
+
+	if bounded := a.bound(limit); bounded != limit {
+		t.SetThrottleCondition(interval, bounded)
+	}
+
+	return a
+}
+
+//Throttle throttles r against the current limit
+func (a *Adaptive) Throttle(r *http.Request) error {
+	return a.t.Throttle(r)
+}
+
+//ThrottleN throttles r, which costs n hits, against the current limit
+func (a *Adaptive) ThrottleN(r *http.Request, n int) error {
+	return a.t.ThrottleN(r, n)
+}
+
+//Clear resets the throttle on the client making r
+func (a *Adaptive) Clear(r *http.Request) error {
+	return a.t.Clear(r)
+}
+
+//Limit returns the current limit
+func (a *Adaptive) Limit() int {
+	limit, _ := a.t.throttleCondition()
+	return limit
+}
+
+//Observe records a request that was let through, how long it took to handle
+//and whether it failed. The limit is adjusted once the current window is over
+func (a *Adaptive) Observe(latency time.Duration, failed bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.requests++
+	a.elapsed += latency
+
+	if failed {
+		a.failures++
+	}
+
+	now := a.t.now()
+
+	if now.Sub(a.started) < a.every || a.requests < a.minSamples {
+		return
+	}
+
+	a.adjust()
+
+	a.started = now
+	a.requests, a.failures, a.elapsed = 0, 0, 0
+}
+
+//adjust raises or cuts the limit based on the window that just ended
+func (a *Adaptive) adjust() {
+	limit, interval := a.t.throttleCondition()
+
+	healthy := a.elapsed/time.Duration(a.requests) <= a.latency &&
+		float64(a.failures)/float64(a.requests) <= a.errorRate
+
+	next := limit + a.increase
+
+	if !healthy {
+		next = int(float64(limit) * a.decrease)
+	}
+
+	if next = a.bound(next); next != limit {
+		a.t.SetThrottleCondition(interval, next)
+	}
+}
+
+func (a *Adaptive) bound(limit int) int {
+	return min(max(limit, a.min), a.max)
+}
+
+//Middleware returns a function that throttles every request before it
+//reaches the wrapped handler, like the package's Middleware, and observes
+//the ones let through. Responses with a 5xx status count as failures
+func (a *Adaptive) Middleware() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if rejected(w, a.Throttle(r)) {
+				return
+			}
+
+			rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+
+			start := time.Now()
+
+			next.ServeHTTP(rec, r)
+
+			a.Observe(time.Since(start), rec.status >= http.StatusInternalServerError)
+		})
+	}
+}
+
+//statusRecorder keeps the status code written by a handler
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (s *statusRecorder) WriteHeader(status int) {
+	s.status = status
+	s.ResponseWriter.WriteHeader(status)
+}
+
+//Unwrap lets http.ResponseController reach the underlying writer
+func (s *statusRecorder) Unwrap() http.ResponseWriter {
+	return s.ResponseWriter
+}
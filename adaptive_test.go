@@ -0,0 +1,152 @@
+package gottle
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+var _ WeightedThrottler = &Adaptive{}
+
+type limitRecorder struct {
+	*fakeRecorder
+	limits []int
+}
+
+func (l *limitRecorder) Limit(name string, limit int) {
+	l.limits = append(l.limits, limit)
+}
+
+func TestAdaptive(t *testing.T) {
+	clock := &fixedClock{time.Date(2017, time.March, 1, 12, 0, 0, 0, time.UTC)}
+
+	recorder := &limitRecorder{fakeRecorder: newFakeRecorder()}
+
+	throttler := NewOneCacheThrottler(
+		Clock(clock), Metrics(recorder), ThrottleCondition(time.Second, 10))
+
+	a := NewAdaptive(throttler, 2, 12,
+		TargetLatency(time.Millisecond*100),
+		MaxErrorRate(0.1),
+		AdjustEvery(time.Second),
+		MinSamples(2))
+
+	window := func(latency time.Duration, failed ...bool) {
+		clock.t = clock.t.Add(time.Second)
+
+		for _, f := range failed {
+			a.Observe(latency, f)
+		}
+	}
+
+	tableTests := []struct {
+		latency time.Duration
+		failed  []bool
+		limit   int
+	}{
+		{time.Millisecond * 50, []bool{false, false}, 11},
+		{time.Millisecond * 50, []bool{false, false}, 12},
+		//The limit never goes over max
+		{time.Millisecond * 50, []bool{false, false}, 12},
+		{time.Millisecond * 500, []bool{false, false}, 6},
+		{time.Millisecond * 50, []bool{false, true}, 3},
+		//Nor under min
+		{time.Millisecond * 50, []bool{true, true}, 2},
+		//Too few samples to tell, they are carried over to the next window
+		{time.Millisecond * 20, []bool{false}, 2},
+		{time.Millisecond * 200, []bool{false}, 2},
+		{time.Millisecond * 20, []bool{false, false}, 3},
+	}
+
+	for i, v := range tableTests {
+		window(v.latency, v.failed...)
+
+		if limit := a.Limit(); limit != v.limit {
+			t.Fatalf(`Limits differ after window %d.. Expected %d.. Got %d`, i+1, v.limit, limit)
+		}
+	}
+
+	if _, interval := throttler.throttleCondition(); interval != time.Second {
+		t.Fatalf(`Intervals differ.. Expected %v.. Got %v`, time.Second, interval)
+	}
+
+	if last := recorder.limits[len(recorder.limits)-1]; last != 3 {
+		t.Fatalf(`Expected the current limit to have been reported.. Got %d`, last)
+	}
+}
+
+func TestNewAdaptive_bounds(t *testing.T) {
+	a := NewAdaptive(NewOneCacheThrottler(ThrottleCondition(time.Second, 100)), 5, 50)
+
+	if limit := a.Limit(); limit != 50 {
+		t.Fatalf(`Limits differ.. Expected %d.. Got %d`, 50, limit)
+	}
+}
+
+func TestAdaptive_Middleware(t *testing.T) {
+	clock := &fixedClock{time.Date(2017, time.March, 1, 12, 0, 0, 0, time.UTC)}
+
+	a := NewAdaptive(NewOneCacheThrottler(Clock(clock), ThrottleCondition(time.Second, 4)),
+		1, 10, AdjustEvery(time.Second), MinSamples(1))
+
+	status := http.StatusInternalServerError
+
+	handler := a.Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}))
+
+	serve := func() int {
+		r := httptest.NewRequest(http.MethodGet, "/oops", nil)
+		r.Header.Set(xForwardedFor, "123.456.789.000")
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+
+		return w.Code
+	}
+
+	clock.t = clock.t.Add(time.Second)
+
+	//The failure is measured by the middleware and cuts the limit
+	if code := serve(); code != status {
+		t.Fatalf(`Status codes differ.. Expected %d.. Got %d`, status, code)
+	}
+
+	if limit := a.Limit(); limit != 2 {
+		t.Fatalf(`Limits differ.. Expected %d.. Got %d`, 2, limit)
+	}
+
+	status = http.StatusNoContent
+
+	serve()
+
+	if code := serve(); code != http.StatusTooManyRequests {
+		t.Fatalf(`Status codes differ.. Expected %d.. Got %d`, http.StatusTooManyRequests, code)
+	}
+}
+
+func TestNewAdaptive_options(t *testing.T) {
+	tableTests := []struct {
+		opts      []AdaptiveOption
+		decrease  float64
+		increase  int
+		errorRate float64
+	}{
+		{[]AdaptiveOption{MultiplicativeDecrease(1)}, defaultMultiplicativeDecrease, 1, defaultMaxErrorRate},
+		{[]AdaptiveOption{MultiplicativeDecrease(-2)}, defaultMultiplicativeDecrease, 1, defaultMaxErrorRate},
+		{[]AdaptiveOption{AdditiveIncrease(0)}, defaultMultiplicativeDecrease, 1, defaultMaxErrorRate},
+		{[]AdaptiveOption{MaxErrorRate(-1)}, defaultMultiplicativeDecrease, 1, 0},
+		{[]AdaptiveOption{MaxErrorRate(3)}, defaultMultiplicativeDecrease, 1, 1},
+		{[]AdaptiveOption{MultiplicativeDecrease(0.8), MaxErrorRate(0.2)}, 0.8, 1, 0.2},
+	}
+
+	for i, v := range tableTests {
+		a := NewAdaptive(NewOneCacheThrottler(), 1, 10, v.opts...)
+
+		if a.decrease != v.decrease || a.increase != v.increase || a.errorRate != v.errorRate {
+			t.Fatalf(`Options differ for case %d.. Expected %v, %d, %v.. Got %v, %d, %v`,
+				i+1, v.decrease, v.increase, v.errorRate, a.decrease, a.increase, a.errorRate)
+		}
+	}
+}
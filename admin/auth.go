@@ -0,0 +1,46 @@
+package admin
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+)
+
+//Authorizer decides if a request may use the admin endpoints
+type Authorizer interface {
+	Authorize(r *http.Request) bool
+}
+
+//AuthorizerFunc is an adapter to allow ordinary functions be used as an Authorizer
+type AuthorizerFunc func(r *http.Request) bool
+
+//Authorize calls f(r)
+func (f AuthorizerFunc) Authorize(r *http.Request) bool {
+	return f(r)
+}
+
+type denyAll struct{}
+
+func (denyAll) Authorize(r *http.Request) bool {
+	return false
+}
+
+type bearerToken []byte
+
+//BearerToken returns an Authorizer that only lets in requests
+//with an "Authorization: Bearer <token>" header
+func BearerToken(token string) Authorizer {
+	return bearerToken(token)
+}
+
+func (b bearerToken) Authorize(r *http.Request) bool {
+	const scheme = "Bearer "
+
+	header := r.Header.Get("Authorization")
+
+	if len(b) == 0 || !strings.HasPrefix(header, scheme) {
+		return false
+	}
+
+	return subtle.ConstantTimeCompare([]byte(header[len(scheme):]), b) == 1
+}
@@ -0,0 +1,30 @@
+package admin
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestBearerToken(t *testing.T) {
+	cases := []struct {
+		token, header string
+		expected      bool
+	}{
+		{"secret", "Bearer secret", true},
+		{"secret", "Bearer oops", false},
+		{"secret", "secret", false},
+		{"secret", "", false},
+		{"", "Bearer ", false},
+	}
+
+	for _, v := range cases {
+		r := httptest.NewRequest(http.MethodGet, "/keys", nil)
+		r.Header.Set("Authorization", v.header)
+
+		if actual := BearerToken(v.token).Authorize(r); actual != v.expected {
+			t.Fatalf(`Authorization differs for %q with token %q.. Expected %v.. Got %v`,
+				v.header, v.token, v.expected, actual)
+		}
+	}
+}
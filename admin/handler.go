@@ -0,0 +1,263 @@
+//Package admin provides an http.Handler for inspecting and clearing
+//the limits a throttler has placed on clients.
+//
+//Mount it under a prefix, behind an Authorizer :
+//
+//	h := admin.NewHandler(throttler, admin.Authorize(admin.BearerToken(token)))
+//	mux.Handle("/admin/", http.StripPrefix("/admin", h))
+//
+//It serves JSON on the following routes :
+//
+//	GET    /keys/{key}           hits, remaining quota and reset time of a key
+//	DELETE /keys/{key}           clears a key
+//	GET    /keys?prefix=&limited=true
+//	                             lists keys, optionally only the limited ones
+//	DELETE /keys?prefix=         clears every key with the prefix
+//	PUT    /keys/{key}/override  sets a key's limit, {"limit": 5000, "interval": "1h"}
+//	DELETE /keys/{key}/override  removes a key's limit
+//
+//Listing, clearing by prefix and overrides are only available when the
+//backend supports them, a 501 is returned otherwise. That includes throttlers
+//whose store can't list it's keys, see gottle.Enumerator, and throttlers
+//without the gottle.StoredOverrides option.
+//
+//Routes are matched on their method and {key} wildcard by http.ServeMux,
+//which needs Go 1.22 or newer and a go.mod that declares it.
+//Other methods on a route get a 405
+package admin
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/adelowo/gottle"
+)
+
+//Backend is what the handler needs from a throttler.
+//gottle.OnecacheThrottler implements it
+type Backend interface {
+	Status(key string) (gottle.KeyStatus, error)
+	ClearKey(key string) error
+}
+
+//Lister is implemented by backends that can list their keys
+type Lister interface {
+	Keys(prefix string, limitedOnly bool, fn func(gottle.KeyStatus) bool) error
+}
+
+//Resetter is implemented by backends that can clear every key with a prefix
+type Resetter interface {
+	Reset(prefix string) (int, error)
+}
+
+//Overrider is implemented by backends that support per key limits
+type Overrider interface {
+	SetOverride(key string, limit int, interval time.Duration) error
+	RemoveOverride(key string) error
+}
+
+var errNotSupported = errors.New(
+	`admin: The operation is not supported by the throttler`)
+
+//Handler serves the admin endpoints
+type Handler struct {
+	backend    Backend
+	authorizer Authorizer
+	mux        *http.ServeMux
+}
+
+//NewHandler returns a Handler for backend.
+//Every request is denied unless an Authorizer is set with the Authorize option
+func NewHandler(backend Backend, opts ...Option) *Handler {
+	h := &Handler{
+		backend:    backend,
+		authorizer: denyAll{},
+		mux:        http.NewServeMux(),
+	}
+
+	for _, opt := range opts {
+		opt(h)
+	}
+
+	h.mux.HandleFunc("GET /keys", h.list)
+	h.mux.HandleFunc("DELETE /keys", h.reset)
+	h.mux.HandleFunc("GET /keys/{key}", h.status)
+	h.mux.HandleFunc("DELETE /keys/{key}", h.clear)
+	h.mux.HandleFunc("PUT /keys/{key}/override", h.setOverride)
+	h.mux.HandleFunc("DELETE /keys/{key}/override", h.removeOverride)
+
+	return h
+}
+
+//ServeHTTP authorizes r and routes it to the matching endpoint
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !h.authorizer.Authorize(r) {
+		writeError(w, http.StatusForbidden, errors.New(`admin: Forbidden`))
+		return
+	}
+
+	h.mux.ServeHTTP(w, r)
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, code int, err error) {
+	writeJSON(w, code, map[string]string{"error": err.Error()})
+}
+
+func (h *Handler) status(w http.ResponseWriter, r *http.Request) {
+	status, err := h.backend.Status(r.PathValue("key"))
+
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, status)
+}
+
+func (h *Handler) clear(w http.ResponseWriter, r *http.Request) {
+	if err := h.backend.ClearKey(r.PathValue("key")); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) list(w http.ResponseWriter, r *http.Request) {
+	lister, ok := h.backend.(Lister)
+
+	if !ok {
+		writeError(w, http.StatusNotImplemented, errNotSupported)
+		return
+	}
+
+	var limitedOnly bool
+
+	if v := r.URL.Query().Get("limited"); v != "" {
+		var err error
+
+		if limitedOnly, err = strconv.ParseBool(v); err != nil {
+			writeError(w, http.StatusBadRequest, errors.New(`admin: limited must be a boolean`))
+			return
+		}
+	}
+
+	keys := []gottle.KeyStatus{}
+
+	err := lister.Keys(r.URL.Query().Get("prefix"), limitedOnly, func(status gottle.KeyStatus) bool {
+		keys = append(keys, status)
+		return true
+	})
+
+	if err != nil {
+		writeError(w, statusOf(err), err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"keys": keys})
+}
+
+func (h *Handler) reset(w http.ResponseWriter, r *http.Request) {
+	resetter, ok := h.backend.(Resetter)
+
+	if !ok {
+		writeError(w, http.StatusNotImplemented, errNotSupported)
+		return
+	}
+
+	//An explicit, even if empty, prefix guards against wiping every key by accident
+	if !r.URL.Query().Has("prefix") {
+		writeError(w, http.StatusBadRequest, errors.New(`admin: A prefix is required`))
+		return
+	}
+
+	n, err := resetter.Reset(r.URL.Query().Get("prefix"))
+
+	if err != nil {
+		writeError(w, statusOf(err), err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]int{"cleared": n})
+}
+
+//statusOf maps the errors of the optional operations to a status code
+func statusOf(err error) int {
+	switch err {
+	case gottle.ErrEnumerationNotSupported, gottle.ErrOverridesNotStored:
+		return http.StatusNotImplemented
+	case gottle.ErrInvalidOverride:
+		return http.StatusBadRequest
+	}
+
+	return http.StatusInternalServerError
+}
+
+type overrideRequest struct {
+	Limit    int    `json:"limit"`
+	Interval string `json:"interval"`
+}
+
+func (h *Handler) setOverride(w http.ResponseWriter, r *http.Request) {
+	overrider, ok := h.backend.(Overrider)
+
+	if !ok {
+		writeError(w, http.StatusNotImplemented, errNotSupported)
+		return
+	}
+
+	var req overrideRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, errors.New(`admin: The body must be valid JSON`))
+		return
+	}
+
+	if req.Limit < 1 {
+		writeError(w, http.StatusBadRequest, errors.New(`admin: limit must be greater than zero`))
+		return
+	}
+
+	var interval time.Duration
+
+	if req.Interval != "" {
+		var err error
+
+		if interval, err = time.ParseDuration(req.Interval); err != nil || interval <= 0 {
+			writeError(w, http.StatusBadRequest, errors.New(`admin: interval must be a positive duration`))
+			return
+		}
+	}
+
+	if err := overrider.SetOverride(r.PathValue("key"), req.Limit, interval); err != nil {
+		writeError(w, statusOf(err), err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) removeOverride(w http.ResponseWriter, r *http.Request) {
+	overrider, ok := h.backend.(Overrider)
+
+	if !ok {
+		writeError(w, http.StatusNotImplemented, errNotSupported)
+		return
+	}
+
+	if err := overrider.RemoveOverride(r.PathValue("key")); err != nil {
+		writeError(w, statusOf(err), err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,279 @@
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/adelowo/gottle"
+)
+
+var _ Backend = &gottle.OnecacheThrottler{}
+var _ Lister = &gottle.OnecacheThrottler{}
+var _ Resetter = &gottle.OnecacheThrottler{}
+var _ Overrider = &gottle.OnecacheThrottler{}
+
+var allowAll = AuthorizerFunc(func(r *http.Request) bool { return true })
+
+//fakeBackend supports every optional operation
+type fakeBackend struct {
+	keys      map[string]gottle.KeyStatus
+	overrides map[string]int
+	reset     string
+}
+
+func newFakeBackend() *fakeBackend {
+	return &fakeBackend{
+		keys: map[string]gottle.KeyStatus{
+			"login-1": {Key: "login-1", Hits: 5, Limit: 5, Limited: true},
+			"login-2": {Key: "login-2", Hits: 1, Limit: 5, Remaining: 4},
+			"api-1":   {Key: "api-1", Hits: 9, Limit: 9, Limited: true},
+		},
+		overrides: make(map[string]int),
+	}
+}
+
+func (f *fakeBackend) Status(key string) (gottle.KeyStatus, error) {
+	return f.keys[key], nil
+}
+
+func (f *fakeBackend) ClearKey(key string) error {
+	delete(f.keys, key)
+	return nil
+}
+
+func (f *fakeBackend) Keys(prefix string, limitedOnly bool, fn func(gottle.KeyStatus) bool) error {
+	for _, key := range []string{"api-1", "login-1", "login-2"} {
+		status, ok := f.keys[key]
+
+		if !ok || !strings.HasPrefix(key, prefix) || (limitedOnly && !status.Limited) {
+			continue
+		}
+
+		if !fn(status) {
+			break
+		}
+	}
+
+	return nil
+}
+
+func (f *fakeBackend) Reset(prefix string) (int, error) {
+	f.reset = prefix
+	return 2, nil
+}
+
+func (f *fakeBackend) SetOverride(key string, limit int, interval time.Duration) error {
+	f.overrides[key] = limit
+	return nil
+}
+
+func (f *fakeBackend) RemoveOverride(key string) error {
+	delete(f.overrides, key)
+	return nil
+}
+
+func serve(h http.Handler, method, target, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(method, target, strings.NewReader(body)))
+	return w
+}
+
+func TestHandler_deniesByDefault(t *testing.T) {
+	h := NewHandler(newFakeBackend())
+
+	if w := serve(h, http.MethodGet, "/keys/login-1", ""); w.Code != http.StatusForbidden {
+		t.Fatalf(`Status codes differ.. Expected %d.. Got %d`, http.StatusForbidden, w.Code)
+	}
+}
+
+func TestHandler_methodNotAllowed(t *testing.T) {
+	h := NewHandler(newFakeBackend(), Authorize(allowAll))
+
+	w := serve(h, http.MethodPost, "/keys/login-1", "")
+
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Fatalf(`Status codes differ.. Expected %d.. Got %d`, http.StatusMethodNotAllowed, w.Code)
+	}
+
+	if allow := w.Header().Get("Allow"); !strings.Contains(allow, http.MethodGet) ||
+		!strings.Contains(allow, http.MethodDelete) {
+		t.Fatalf(`Expected the methods of the route to be allowed.. Got %s`, allow)
+	}
+}
+
+func TestHandler_withThrottler(t *testing.T) {
+	throttler := gottle.NewOneCacheThrottler(gottle.ThrottleCondition(time.Minute, 1))
+
+	r := httptest.NewRequest(http.MethodGet, "/oops", nil)
+	r.Header.Set("X-Forwarded-For", "123.456.789.000")
+
+	throttler.Throttle(r)
+
+	h := NewHandler(throttler, Authorize(allowAll))
+
+	w := serve(h, http.MethodGet, "/keys/123.456.789.000", "")
+
+	if w.Code != http.StatusOK {
+		t.Fatalf(`Status codes differ.. Expected %d.. Got %d`, http.StatusOK, w.Code)
+	}
+
+	var status gottle.KeyStatus
+
+	if err := json.NewDecoder(w.Body).Decode(&status); err != nil {
+		t.Fatalf(`An error occurred while decoding the response.. %v`, err)
+	}
+
+	if status.Hits != 1 || !status.Limited || status.ResetAt.IsZero() {
+		t.Fatalf(`The status was not reported properly.. Got %+v`, status)
+	}
+
+	if w := serve(h, http.MethodDelete, "/keys/123.456.789.000", ""); w.Code != http.StatusNoContent {
+		t.Fatalf(`Status codes differ.. Expected %d.. Got %d`, http.StatusNoContent, w.Code)
+	}
+
+	if throttler.IsRateLimited(r) {
+		t.Fatal(`The client is not supposed to be limited after it's key was cleared`)
+	}
+}
+
+func TestHandler_list(t *testing.T) {
+	h := NewHandler(newFakeBackend(), Authorize(allowAll))
+
+	w := serve(h, http.MethodGet, "/keys?prefix=login-&limited=true", "")
+
+	if w.Code != http.StatusOK {
+		t.Fatalf(`Status codes differ.. Expected %d.. Got %d`, http.StatusOK, w.Code)
+	}
+
+	var res struct {
+		Keys []gottle.KeyStatus `json:"keys"`
+	}
+
+	if err := json.NewDecoder(w.Body).Decode(&res); err != nil {
+		t.Fatalf(`An error occurred while decoding the response.. %v`, err)
+	}
+
+	if len(res.Keys) != 1 || res.Keys[0].Key != "login-1" {
+		t.Fatalf(`Expected only login-1 to be listed.. Got %+v`, res.Keys)
+	}
+
+	if w := serve(h, http.MethodGet, "/keys?limited=oops", ""); w.Code != http.StatusBadRequest {
+		t.Fatalf(`Status codes differ.. Expected %d.. Got %d`, http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestHandler_reset(t *testing.T) {
+	backend := newFakeBackend()
+
+	h := NewHandler(backend, Authorize(allowAll))
+
+	if w := serve(h, http.MethodDelete, "/keys", ""); w.Code != http.StatusBadRequest {
+		t.Fatalf(`A reset without a prefix is supposed to be rejected.. Got %d`, w.Code)
+	}
+
+	w := serve(h, http.MethodDelete, "/keys?prefix=login-", "")
+
+	if w.Code != http.StatusOK || backend.reset != "login-" {
+		t.Fatalf(`Expected login- to be reset.. Got %d, %q`, w.Code, backend.reset)
+	}
+}
+
+func TestHandler_override(t *testing.T) {
+	backend := newFakeBackend()
+
+	h := NewHandler(backend, Authorize(allowAll))
+
+	cases := []struct {
+		body string
+		code int
+	}{
+		{`{"limit": 5000, "interval": "1h"}`, http.StatusNoContent},
+		{`{"limit": 0}`, http.StatusBadRequest},
+		{`{"limit": 10, "interval": "oops"}`, http.StatusBadRequest},
+		{`oops`, http.StatusBadRequest},
+	}
+
+	for _, v := range cases {
+		if w := serve(h, http.MethodPut, "/keys/login-1/override", v.body); w.Code != v.code {
+			t.Fatalf(`Status codes differ for %s.. Expected %d.. Got %d`, v.body, v.code, w.Code)
+		}
+	}
+
+	if backend.overrides["login-1"] != 5000 {
+		t.Fatalf(`Expected the override to be set.. Got %v`, backend.overrides)
+	}
+
+	if w := serve(h, http.MethodDelete, "/keys/login-1/override", ""); w.Code != http.StatusNoContent {
+		t.Fatalf(`Status codes differ.. Expected %d.. Got %d`, http.StatusNoContent, w.Code)
+	}
+
+	if _, ok := backend.overrides["login-1"]; ok {
+		t.Fatal(`Expected the override to be removed`)
+	}
+}
+
+func TestHandler_notSupported(t *testing.T) {
+	//A backend with none of the optional operations
+	var backend struct{ Backend }
+
+	h := NewHandler(backend, Authorize(allowAll))
+
+	cases := []struct {
+		method, target, body string
+	}{
+		{http.MethodGet, "/keys", ""},
+		{http.MethodDelete, "/keys?prefix=", ""},
+		{http.MethodPut, "/keys/login-1/override", `{"limit": 10}`},
+		{http.MethodDelete, "/keys/login-1/override", ""},
+	}
+
+	for _, v := range cases {
+		if w := serve(h, v.method, v.target, v.body); w.Code != http.StatusNotImplemented {
+			t.Fatalf(`Status codes differ for %s %s.. Expected %d.. Got %d`,
+				v.method, v.target, http.StatusNotImplemented, w.Code)
+		}
+	}
+}
+
+func TestHandler_storeCannotEnumerate(t *testing.T) {
+	//The default in memory store can't list it's keys
+	h := NewHandler(gottle.NewOneCacheThrottler(), Authorize(allowAll))
+
+	for _, method := range []string{http.MethodGet, http.MethodDelete} {
+		if w := serve(h, method, "/keys?prefix=", ""); w.Code != http.StatusNotImplemented {
+			t.Fatalf(`Status codes differ for %s.. Expected %d.. Got %d`,
+				method, http.StatusNotImplemented, w.Code)
+		}
+	}
+}
+
+func TestHandler_overrideWithThrottler(t *testing.T) {
+	throttler := gottle.NewOneCacheThrottler(
+		gottle.StoredOverrides(true),
+		gottle.ThrottleCondition(time.Minute, 1))
+
+	h := NewHandler(throttler, Authorize(allowAll))
+
+	w := serve(h, http.MethodPut, "/keys/123.456.789.000/override", `{"limit": 10, "interval": "1h"}`)
+
+	if w.Code != http.StatusNoContent {
+		t.Fatalf(`Status codes differ.. Expected %d.. Got %d`, http.StatusNoContent, w.Code)
+	}
+
+	if status, _ := throttler.Status("123.456.789.000"); status.Limit != 10 {
+		t.Fatalf(`Limits differ.. Expected %d.. Got %d`, 10, status.Limit)
+	}
+
+	//Without stored overrides there is nowhere to keep them
+	h = NewHandler(gottle.NewOneCacheThrottler(), Authorize(allowAll))
+
+	w = serve(h, http.MethodPut, "/keys/123.456.789.000/override", `{"limit": 10}`)
+
+	if w.Code != http.StatusNotImplemented {
+		t.Fatalf(`Status codes differ.. Expected %d.. Got %d`, http.StatusNotImplemented, w.Code)
+	}
+}
@@ -0,0 +1,11 @@
+package admin
+
+//Option configures a Handler
+type Option func(*Handler)
+
+//Authorize is an Option that sets who may use the handler
+func Authorize(a Authorizer) Option {
+	return func(h *Handler) {
+		h.authorizer = a
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/adelowo/gottle"
+	"github.com/adelowo/gottle/config"
+	"github.com/adelowo/gottle/memcached"
+	"github.com/adelowo/gottle/persistent"
+	"github.com/adelowo/gottle/redis"
+	"github.com/bradfitz/gomemcache/memcache"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+//backendConfig describes the store the services use
+type backendConfig struct {
+	config        string
+	limiter       string
+	store         string
+	redisAddr     string
+	memcachedAddr string
+	path          string
+	prefix        string
+	limit         int
+	interval      time.Duration
+}
+
+//throttler returns a throttler on top of the configured store and
+//a function that releases the store once done
+func (c *backendConfig) throttler() (*gottle.OnecacheThrottler, func(), error) {
+	if c.config != "" {
+		return c.fromConfig()
+	}
+
+	opts := []gottle.Option{gottle.ThrottleCondition(c.interval, c.limit)}
+
+	switch c.store {
+	case "redis":
+		client := goredis.NewClient(&goredis.Options{Addr: c.redisAddr})
+
+		opts = append(opts, gottle.Store(redis.New(client, redis.Prefix(c.prefix))))
+
+		return gottle.NewOneCacheThrottler(opts...), func() { client.Close() }, nil
+
+	case "memcached":
+		store := memcached.New(memcache.New(c.memcachedAddr), c.interval,
+			memcached.Prefix(c.prefix))
+
+		return gottle.NewOneCacheThrottler(append(opts, gottle.Store(store))...), func() {}, nil
+
+	case "persistent":
+		if c.path == "" {
+			return nil, nil, fmt.Errorf("-path is required for the persistent store")
+		}
+
+		store, err := persistent.New(c.path, persistent.SnapshotInterval(0))
+
+		if err != nil {
+			return nil, nil, err
+		}
+
+		//Closing takes the snapshot that saves any change made
+		return gottle.NewOneCacheThrottler(append(opts, gottle.Store(store))...),
+			func() { store.Close() }, nil
+	}
+
+	return nil, nil, fmt.Errorf("unknown store %q", c.store)
+}
+
+//fromConfig returns the throttler of a limiter described in the
+//configuration file the services use. The limiter can be left out
+//if the file describes only one
+func (c *backendConfig) fromConfig() (*gottle.OnecacheThrottler, func(), error) {
+	cfg, err := config.Load(c.config)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	name := c.limiter
+
+	if name == "" {
+		if len(cfg.Limiters) > 1 {
+			return nil, nil, fmt.Errorf("-limiter is required as %s describes %d limiters",
+				c.config, len(cfg.Limiters))
+		}
+
+		name = cfg.Limiters[0].Name
+	}
+
+	policy, err := cfg.Build()
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	throttler := policy.Throttler(name)
+
+	if throttler == nil {
+		policy.Close()
+		return nil, nil, fmt.Errorf("unknown limiter %q", name)
+	}
+
+	return throttler, func() { policy.Close() }, nil
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/adelowo/gottle"
+)
+
+//dump is the JSON written by dump and read by restore
+type dump struct {
+	Keys []gottle.KeyStatus `json:"keys"`
+}
+
+type command struct {
+	throttler *gottle.OnecacheThrottler
+	stdin     io.Reader
+	stdout    io.Writer
+}
+
+func (c *command) key(args []string) (string, error) {
+	if len(args) != 1 {
+		return "", errors.New("a single key is required")
+	}
+
+	return args[0], nil
+}
+
+func (c *command) inspect(args []string) error {
+	key, err := c.key(args)
+
+	if err != nil {
+		return err
+	}
+
+	status, err := c.throttler.Status(key)
+
+	if err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(c.stdout)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(status)
+}
+
+func (c *command) clear(args []string) error {
+	key, err := c.key(args)
+
+	if err != nil {
+		return err
+	}
+
+	return c.throttler.ClearKey(key)
+}
+
+//keys returns every key with prefix
+func (c *command) keys(prefix string) ([]gottle.KeyStatus, error) {
+	var keys []gottle.KeyStatus
+
+	err := c.throttler.Keys(prefix, false, func(status gottle.KeyStatus) bool {
+		keys = append(keys, status)
+		return true
+	})
+
+	return keys, err
+}
+
+func (c *command) top(args []string) error {
+	fs := flag.NewFlagSet("top", flag.ContinueOnError)
+
+	n := fs.Int("n", 10, "number of keys to list")
+	prefix := fs.String("prefix", "", "only list keys with this prefix")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *n < 0 {
+		return errors.New("the number of keys can't be negative")
+	}
+
+	keys, err := c.keys(*prefix)
+
+	if err != nil {
+		return err
+	}
+
+	sort.SliceStable(keys, func(i, j int) bool {
+		return keys[i].Hits > keys[j].Hits
+	})
+
+	if len(keys) > *n {
+		keys = keys[:*n]
+	}
+
+	w := tabwriter.NewWriter(c.stdout, 0, 4, 2, ' ', 0)
+
+	fmt.Fprintln(w, "KEY\tHITS\tREMAINING\tLIMITED\tRESET AT")
+
+	for _, k := range keys {
+		fmt.Fprintf(w, "%s\t%d\t%d\t%v\t%s\n",
+			k.Key, k.Hits, k.Remaining, k.Limited, k.ResetAt.Format(time.RFC3339))
+	}
+
+	return w.Flush()
+}
+
+func (c *command) dump() error {
+	keys, err := c.keys("")
+
+	if err != nil {
+		return err
+	}
+
+	if keys == nil {
+		keys = []gottle.KeyStatus{}
+	}
+
+	return json.NewEncoder(c.stdout).Encode(dump{Keys: keys})
+}
+
+func (c *command) restore() error {
+	var d dump
+
+	if err := json.NewDecoder(c.stdin).Decode(&d); err != nil {
+		return err
+	}
+
+	for _, status := range d.Keys {
+		if err := c.throttler.Restore(status); err != nil {
+			return fmt.Errorf("restoring %s: %v", status.Key, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,138 @@
+//Command gottlectl inspects and manages the state kept by gottle throttlers.
+//
+//It talks to the same store the services do, so point it at the same
+//backend and pass the same limit and interval :
+//
+//	gottlectl [flags] inspect <key>     prints the state of a key
+//	gottlectl [flags] clear <key>       clears a key
+//	gottlectl [flags] top [-n 10] [-prefix p]
+//	                                    lists the keys with the most hits
+//	gottlectl [flags] dump              writes the state of every key to stdout as JSON
+//	gottlectl [flags] restore           reads a dump from stdin and writes it to the store
+//
+//The store, limit and interval can also be read from the configuration file
+//the services are built from, see the config package, with -config and
+//-limiter. Keys are then prefixed with the limiter's name.
+//
+//Every flag can also be set with the GOTTLE_ environment variable
+//named after it, e.g GOTTLE_REDIS_ADDR for -redis-addr.
+//Listing keys, as top and dump do, needs a store that supports it
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+//env returns the value of the GOTTLE_ variable for flag name, or def if it is not set
+func env(name, def string) string {
+	key := "GOTTLE_" + strings.ToUpper(strings.Replace(name, "-", "_", -1))
+
+	if v, ok := os.LookupEnv(key); ok {
+		return v
+	}
+
+	return def
+}
+
+func envDuration(name string, def time.Duration) time.Duration {
+	d, err := time.ParseDuration(env(name, def.String()))
+
+	if err != nil {
+		return def
+	}
+
+	return d
+}
+
+func envInt(name string, def int) int {
+	var n int
+
+	if _, err := fmt.Sscan(env(name, fmt.Sprint(def)), &n); err != nil {
+		return def
+	}
+
+	return n
+}
+
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("gottlectl", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+
+	cfg := new(backendConfig)
+
+	fs.StringVar(&cfg.config, "config", env("config", ""),
+		"a gottle configuration file describing the store and limiters. It replaces the other flags")
+	fs.StringVar(&cfg.limiter, "limiter", env("limiter", ""),
+		"the limiter of the configuration file to work with")
+	fs.StringVar(&cfg.store, "store", env("store", "redis"),
+		"the store the throttlers use. One of redis, memcached or persistent")
+	fs.StringVar(&cfg.redisAddr, "redis-addr", env("redis-addr", "localhost:6379"),
+		"address of the Redis server")
+	fs.StringVar(&cfg.memcachedAddr, "memcached-addr", env("memcached-addr", "localhost:11211"),
+		"address of the memcached server")
+	fs.StringVar(&cfg.path, "path", env("path", ""),
+		"path of the persistent store's snapshot. The service must be stopped")
+	fs.StringVar(&cfg.prefix, "prefix", env("prefix", "gottle:"),
+		"prefix the store adds to every key")
+	fs.IntVar(&cfg.limit, "limit", envInt("limit", 10),
+		"maximum number of requests within the interval")
+	fs.DurationVar(&cfg.interval, "interval", envDuration("interval", time.Minute*10),
+		"the interval requests are counted over")
+
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: gottlectl [flags] inspect|clear|top|dump|restore [args]")
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+
+	throttler, closer, err := cfg.throttler()
+
+	if err != nil {
+		fmt.Fprintf(stderr, "gottlectl: %v\n", err)
+		return 1
+	}
+
+	defer closer()
+
+	cmd := &command{throttler: throttler, stdin: stdin, stdout: stdout}
+
+	switch fs.Arg(0) {
+	case "inspect":
+		err = cmd.inspect(fs.Args()[1:])
+	case "clear":
+		err = cmd.clear(fs.Args()[1:])
+	case "top":
+		err = cmd.top(fs.Args()[1:])
+	case "dump":
+		err = cmd.dump()
+	case "restore":
+		err = cmd.restore()
+	default:
+		fs.Usage()
+		return 2
+	}
+
+	if err != nil {
+		fmt.Fprintf(stderr, "gottlectl: %v\n", err)
+		return 1
+	}
+
+	return 0
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/adelowo/gottle"
+	"github.com/adelowo/gottle/redis"
+	"github.com/alicebob/miniredis/v2"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+//setUp returns a throttler standing in for a service and the flags
+//gottlectl needs to share it's store
+func setUp(t *testing.T) (*gottle.OnecacheThrottler, []string) {
+	mr := miniredis.RunT(t)
+
+	client := goredis.NewClient(&goredis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	throttler := gottle.NewOneCacheThrottler(
+		gottle.Store(redis.New(client)),
+		gottle.ThrottleCondition(time.Minute, 2))
+
+	return throttler, []string{"-store", "redis", "-redis-addr", mr.Addr(),
+		"-limit", "2", "-interval", "1m"}
+}
+
+func request(ip string) *http.Request {
+	r := httptest.NewRequest(http.MethodGet, "/oops", nil)
+	r.Header.Set("X-Forwarded-For", ip)
+	return r
+}
+
+func runCmd(t *testing.T, stdin string, args ...string) (string, int) {
+	var stdout, stderr bytes.Buffer
+
+	code := run(args, strings.NewReader(stdin), &stdout, &stderr)
+
+	if code != 0 {
+		return stderr.String(), code
+	}
+
+	return stdout.String(), code
+}
+
+func TestRun_inspectAndClear(t *testing.T) {
+	throttler, flags := setUp(t)
+
+	r := request("123.456.789.000")
+
+	for i := 0; i < 2; i++ {
+		throttler.Throttle(r)
+	}
+
+	out, code := runCmd(t, "", append(flags, "inspect", "123.456.789.000")...)
+
+	if code != 0 {
+		t.Fatalf(`inspect failed with %d.. %s`, code, out)
+	}
+
+	var status gottle.KeyStatus
+
+	if err := json.Unmarshal([]byte(out), &status); err != nil {
+		t.Fatalf(`An error occurred while decoding the output.. %v`, err)
+	}
+
+	if status.Hits != 2 || !status.Limited {
+		t.Fatalf(`The key was not inspected properly.. Got %+v`, status)
+	}
+
+	if out, code := runCmd(t, "", append(flags, "clear", "123.456.789.000")...); code != 0 {
+		t.Fatalf(`clear failed with %d.. %s`, code, out)
+	}
+
+	if throttler.IsRateLimited(r) {
+		t.Fatal(`The client is not supposed to be limited after it's key was cleared`)
+	}
+}
+
+func TestRun_topAndDump(t *testing.T) {
+	throttler, flags := setUp(t)
+
+	throttler.Throttle(request("10.0.0.1"))
+
+	for i := 0; i < 2; i++ {
+		throttler.Throttle(request("10.0.0.2"))
+	}
+
+	out, code := runCmd(t, "", append(flags, "top", "-n", "1")...)
+
+	if code != 0 {
+		t.Fatalf(`top failed with %d.. %s`, code, out)
+	}
+
+	if !strings.Contains(out, "10.0.0.2") || strings.Contains(out, "10.0.0.1") {
+		t.Fatalf(`Expected only the busiest key to be listed.. Got %s`, out)
+	}
+
+	out, code = runCmd(t, "", append(flags, "dump")...)
+
+	if code != 0 {
+		t.Fatalf(`dump failed with %d.. %s`, code, out)
+	}
+
+	var d dump
+
+	if err := json.Unmarshal([]byte(out), &d); err != nil {
+		t.Fatalf(`An error occurred while decoding the output.. %v`, err)
+	}
+
+	if len(d.Keys) != 2 {
+		t.Fatalf(`Expected %d keys to be dumped.. Got %d`, 2, len(d.Keys))
+	}
+}
+
+func TestRun_restore(t *testing.T) {
+	throttler, flags := setUp(t)
+
+	input := `{"keys": [{"key": "123.456.789.000", "hits": 2, "reset_at": "` +
+		time.Now().Add(time.Second*30).Format(time.RFC3339Nano) + `"}]}`
+
+	if out, code := runCmd(t, input, append(flags, "restore")...); code != 0 {
+		t.Fatalf(`restore failed with %d.. %s`, code, out)
+	}
+
+	if !throttler.IsRateLimited(request("123.456.789.000")) {
+		t.Fatal(`The restored client is supposed to be limited`)
+	}
+}
+
+func TestRun_usage(t *testing.T) {
+	_, flags := setUp(t)
+
+	cases := [][]string{
+		{},
+		{"oops"},
+		{"inspect"},
+		{"top", "-n", "-1"},
+		{"-store", "oops", "inspect", "key"},
+	}
+
+	for _, args := range cases {
+		if _, code := runCmd(t, "", append(flags, args...)...); code == 0 {
+			t.Fatalf(`Expected %v to fail`, args)
+		}
+	}
+}
+
+func TestRun_config(t *testing.T) {
+	mr := miniredis.RunT(t)
+
+	path := filepath.Join(t.TempDir(), "gottle.yaml")
+
+	err := os.WriteFile(path, []byte(`
+backend:
+  type: redis
+  addr: `+mr.Addr()+`
+limiters:
+  - name: login
+    limit: 2
+    interval: 1m
+  - name: api
+    limit: 100
+    interval: 1h
+`), 0600)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out, code := runCmd(t, "", "-config", path, "-limiter", "login", "inspect", "login:123.456.789.000")
+
+	if code != 0 {
+		t.Fatalf(`inspect failed with %d.. %s`, code, out)
+	}
+
+	var status gottle.KeyStatus
+
+	if err := json.Unmarshal([]byte(out), &status); err != nil {
+		t.Fatalf(`An error occurred while decoding the output.. %v`, err)
+	}
+
+	if status.Limit != 2 {
+		t.Fatalf(`Expected the limit of the login limiter.. Got %+v`, status)
+	}
+
+	//The limiter is ambiguous
+	if _, code := runCmd(t, "", "-config", path, "inspect", "key"); code == 0 {
+		t.Fatal(`Expected inspect to fail without a limiter`)
+	}
+}
@@ -0,0 +1,399 @@
+//Package config builds throttlers and their middleware from a YAML or JSON file.
+//
+//A file describes the backend every limiter stores it's hits in and the
+//limiters themselves :
+//
+//	backend:
+//	  type: redis            # memory, sharded, redis, memcached or persistent
+//	  addr: localhost:6379   # redis and memcached
+//	  prefix: "gottle:"      # redis and memcached
+//	  path: /var/lib/gottle  # persistent
+//
+//	limiters:
+//	  - name: login
+//	    algorithm: counter   # counter, the default, or quota
+//	    limit: 5
+//	    interval: 1m         # counter
+//	    period: month        # quota, day, week or month
+//	    location: UTC        # quota, the time zone windows start in
+//	    key:
+//	      source: ip         # ip or header
+//	      header: X-API-Key  # with the header source
+//	    ip:
+//	      provider: trusted  # real, remote or trusted
+//	      trusted_proxies: [10.0.0.0/8]
+//	    routes:
+//	      - path: /login     # a trailing * matches every path with the prefix
+//	        methods: [POST]
+//	        cost: 1            # hits a matching request counts for
+//	    allow: [127.0.0.1]   # never limited
+//	    deny: [1.2.3.0/24]   # always rejected
+//	    shadow: false
+//
+//JSON files use the same field names. Every problem found in a file is
+//reported at once, each with the path of the field it is about.
+//
+//A Reloader swaps in a new policy when the file changes, or when it is
+//handed a new configuration, without a restart
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/adelowo/gottle"
+	"gopkg.in/yaml.v3"
+)
+
+//Backend types
+const (
+	BackendMemory     = "memory"
+	BackendSharded    = "sharded"
+	BackendRedis      = "redis"
+	BackendMemcached  = "memcached"
+	BackendPersistent = "persistent"
+)
+
+//Algorithms
+const (
+	//AlgorithmCounter counts the hits of a client and limits it once they
+	//reach the limit, until interval has passed since the last one.
+	//It is what gottle.OnecacheThrottler implements
+	AlgorithmCounter = "counter"
+
+	//AlgorithmQuota counts the hits of a client in calendar windows,
+	//limiting it until the window it reached the limit in ends.
+	//It is gottle.OnecacheThrottler in quota mode, see gottle.Quota
+	AlgorithmQuota = "quota"
+)
+
+//Quota periods
+const (
+	PeriodDay   = "day"
+	PeriodWeek  = "week"
+	PeriodMonth = "month"
+)
+
+var periods = map[string]gottle.Period{
+	PeriodDay:   gottle.Day,
+	PeriodWeek:  gottle.Week,
+	PeriodMonth: gottle.Month,
+}
+
+//Key sources
+const (
+	KeySourceIP     = "ip"
+	KeySourceHeader = "header"
+)
+
+//IP providers
+const (
+	IPProviderReal    = "real"
+	IPProviderRemote  = "remote"
+	IPProviderTrusted = "trusted"
+)
+
+//Duration is a time.Duration written as a string, such as "1m30s"
+type Duration time.Duration
+
+//UnmarshalYAML parses a duration string
+func (d *Duration) UnmarshalYAML(value *yaml.Node) error {
+	var s string
+
+	if err := value.Decode(&s); err != nil {
+		return err
+	}
+
+	parsed, err := time.ParseDuration(s)
+
+	if err != nil {
+		return fmt.Errorf("line %d: %q is not a valid duration", value.Line, s)
+	}
+
+	*d = Duration(parsed)
+
+	return nil
+}
+
+//MarshalYAML writes the duration as a string
+func (d Duration) MarshalYAML() (interface{}, error) {
+	return time.Duration(d).String(), nil
+}
+
+//Config describes a set of limiters
+type Config struct {
+	Backend  Backend   `yaml:"backend"`
+	Limiters []Limiter `yaml:"limiters"`
+}
+
+//Backend describes the store limiters keep their hits in
+type Backend struct {
+	Type   string `yaml:"type"`
+	Addr   string `yaml:"addr"`
+	Prefix string `yaml:"prefix"`
+	Path   string `yaml:"path"`
+}
+
+//Limiter describes a throttler and the requests it applies to
+type Limiter struct {
+	Name      string   `yaml:"name"`
+	Algorithm string   `yaml:"algorithm"`
+	Limit     int      `yaml:"limit"`
+	Interval  Duration `yaml:"interval"`
+	Period    string   `yaml:"period"`
+	Location  string   `yaml:"location"`
+	Key       Key      `yaml:"key"`
+	IP        IP       `yaml:"ip"`
+	Routes    []Route  `yaml:"routes"`
+	Allow     []string `yaml:"allow"`
+	Deny      []string `yaml:"deny"`
+	Shadow    bool     `yaml:"shadow"`
+}
+
+//Key describes what identifies a client.
+//Clients are identified by IP if the header is missing from a request
+type Key struct {
+	Source string `yaml:"source"`
+	Header string `yaml:"header"`
+}
+
+//IP describes how the IP of a request is found
+type IP struct {
+	Provider       string   `yaml:"provider"`
+	TrustedProxies []string `yaml:"trusted_proxies"`
+}
+
+//Route matches requests by path and method.
+//A path ending with * matches every path with that prefix and
+//no methods matches every method. Matching requests count for
+//Cost hits, one by default
+type Route struct {
+	Path    string   `yaml:"path"`
+	Methods []string `yaml:"methods"`
+	Cost    int      `yaml:"cost"`
+}
+
+//ValidationError lists every problem found in a configuration
+type ValidationError struct {
+	Problems []string
+}
+
+func (v *ValidationError) Error() string {
+	return "config: invalid configuration:\n\t" + strings.Join(v.Problems, "\n\t")
+}
+
+func (v *ValidationError) add(field, format string, args ...interface{}) {
+	v.Problems = append(v.Problems, field+": "+fmt.Sprintf(format, args...))
+}
+
+//Load reads and validates the configuration in the file at path
+func Load(path string) (*Config, error) {
+	f, err := os.Open(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer f.Close()
+
+	return Read(f)
+}
+
+//Read reads and validates a YAML or JSON configuration.
+//JSON is read as the subset of YAML it is
+func Read(r io.Reader) (*Config, error) {
+	buf, err := io.ReadAll(r)
+
+	if err != nil {
+		return nil, err
+	}
+
+	dec := yaml.NewDecoder(bytes.NewReader(buf))
+	dec.KnownFields(true)
+
+	cfg := new(Config)
+
+	if err := dec.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("config: %v", err)
+	}
+
+	cfg.setDefaults()
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+func (c *Config) setDefaults() {
+	if c.Backend.Type == "" {
+		c.Backend.Type = BackendMemory
+	}
+
+	for i := range c.Limiters {
+		l := &c.Limiters[i]
+
+		if l.Algorithm == "" {
+			l.Algorithm = AlgorithmCounter
+		}
+
+		if l.Key.Source == "" {
+			l.Key.Source = KeySourceIP
+		}
+
+		if l.IP.Provider == "" {
+			l.IP.Provider = IPProviderReal
+		}
+
+		for j := range l.Routes {
+			if l.Routes[j].Cost == 0 {
+				l.Routes[j].Cost = 1
+			}
+		}
+	}
+}
+
+//Validate checks the configuration, returning a *ValidationError
+//that lists every problem found
+func (c *Config) Validate() error {
+	v := new(ValidationError)
+
+	switch c.Backend.Type {
+	case BackendMemory, BackendSharded:
+	case BackendRedis, BackendMemcached:
+		if c.Backend.Addr == "" {
+			v.add("backend.addr", "is required for the %s backend", c.Backend.Type)
+		}
+	case BackendPersistent:
+		if c.Backend.Path == "" {
+			v.add("backend.path", "is required for the persistent backend")
+		}
+	default:
+		v.add("backend.type", "unknown backend %q, must be one of %s", c.Backend.Type,
+			strings.Join([]string{BackendMemory, BackendSharded, BackendRedis,
+				BackendMemcached, BackendPersistent}, ", "))
+	}
+
+	if len(c.Limiters) == 0 {
+		v.add("limiters", "at least one limiter is required")
+	}
+
+	names := make(map[string]bool)
+
+	for i, l := range c.Limiters {
+		field := fmt.Sprintf("limiters[%d]", i)
+
+		if l.Name == "" {
+			v.add(field+".name", "is required")
+		} else if names[l.Name] {
+			v.add(field+".name", "%q is used by another limiter", l.Name)
+		}
+
+		names[l.Name] = true
+
+		l.validate(v, field)
+
+		if l.Algorithm == AlgorithmQuota && c.Backend.Type == BackendMemcached {
+			v.add(field+".algorithm", "quotas can't be kept in memcached, which counts in windows of it's own")
+		}
+	}
+
+	if len(v.Problems) > 0 {
+		return v
+	}
+
+	return nil
+}
+
+func (l Limiter) validate(v *ValidationError, field string) {
+	switch l.Algorithm {
+	case AlgorithmCounter:
+		if l.Interval <= 0 {
+			v.add(field+".interval", "must be a positive duration")
+		}
+
+		if l.Period != "" {
+			v.add(field+".period", "only applies to quotas")
+		}
+
+		if l.Location != "" {
+			v.add(field+".location", "only applies to quotas")
+		}
+	case AlgorithmQuota:
+		if l.Interval != 0 {
+			v.add(field+".interval", "does not apply to quotas, they reset with their period")
+		}
+
+		if _, ok := periods[l.Period]; !ok {
+			v.add(field+".period", "unknown period %q, must be one of %s, %s or %s",
+				l.Period, PeriodDay, PeriodWeek, PeriodMonth)
+		}
+
+		if _, err := time.LoadLocation(l.Location); err != nil {
+			v.add(field+".location", "unknown time zone %q", l.Location)
+		}
+	default:
+		v.add(field+".algorithm", "unknown algorithm %q, must be %s or %s",
+			l.Algorithm, AlgorithmCounter, AlgorithmQuota)
+	}
+
+	if l.Limit < 1 {
+		v.add(field+".limit", "must be greater than zero")
+	}
+
+	switch l.Key.Source {
+	case KeySourceIP:
+	case KeySourceHeader:
+		if l.Key.Header == "" {
+			v.add(field+".key.header", "is required when the key comes from a header")
+		}
+	default:
+		v.add(field+".key.source", "unknown source %q, must be %s or %s",
+			l.Key.Source, KeySourceIP, KeySourceHeader)
+	}
+
+	switch l.IP.Provider {
+	case IPProviderReal, IPProviderRemote:
+		if len(l.IP.TrustedProxies) > 0 {
+			v.add(field+".ip.trusted_proxies", "only apply to the trusted provider")
+		}
+	case IPProviderTrusted:
+		if len(l.IP.TrustedProxies) == 0 {
+			v.add(field+".ip.trusted_proxies", "are required for the trusted provider")
+		}
+
+		validateNetworks(v, field+".ip.trusted_proxies", l.IP.TrustedProxies)
+	default:
+		v.add(field+".ip.provider", "unknown provider %q, must be one of %s, %s or %s",
+			l.IP.Provider, IPProviderReal, IPProviderRemote, IPProviderTrusted)
+	}
+
+	for j, route := range l.Routes {
+		if !strings.HasPrefix(route.Path, "/") {
+			v.add(fmt.Sprintf("%s.routes[%d].path", field, j), "must start with /")
+		}
+
+		if route.Cost < 1 {
+			v.add(fmt.Sprintf("%s.routes[%d].cost", field, j), "must be greater than zero")
+		} else if route.Cost > l.Limit && l.Limit > 0 {
+			v.add(fmt.Sprintf("%s.routes[%d].cost", field, j), "is over the limit, the route would never be allowed")
+		}
+	}
+
+	validateNetworks(v, field+".allow", l.Allow)
+	validateNetworks(v, field+".deny", l.Deny)
+}
+
+func validateNetworks(v *ValidationError, field string, addrs []string) {
+	for i, addr := range addrs {
+		if _, err := gottle.ParseNetworks(addr); err != nil {
+			v.add(fmt.Sprintf("%s[%d]", field, i), "%q is not an IP address or CIDR range", addr)
+		}
+	}
+}
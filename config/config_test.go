@@ -0,0 +1,169 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const sample = `
+backend:
+  type: sharded
+
+limiters:
+  - name: login
+    limit: 5
+    interval: 1m
+    routes:
+      - path: /login
+        methods: [POST]
+  - name: api
+    limit: 100
+    interval: 1h
+    key:
+      source: header
+      header: X-API-Key
+    ip:
+      provider: trusted
+      trusted_proxies: [10.0.0.0/8]
+    routes:
+      - path: /api/*
+    allow: [127.0.0.1]
+    deny: [1.2.3.0/24]
+`
+
+func TestRead(t *testing.T) {
+	cfg, err := Read(strings.NewReader(sample))
+
+	if err != nil {
+		t.Fatalf(`An error occurred while reading the configuration.. %v`, err)
+	}
+
+	if len(cfg.Limiters) != 2 {
+		t.Fatalf(`Expected %d limiters.. Got %d`, 2, len(cfg.Limiters))
+	}
+
+	login := cfg.Limiters[0]
+
+	if login.Limit != 5 || time.Duration(login.Interval) != time.Minute {
+		t.Fatalf(`The limits were not read properly.. Got %+v`, login)
+	}
+
+	//Defaults
+	if login.Algorithm != AlgorithmCounter || login.Key.Source != KeySourceIP ||
+		login.IP.Provider != IPProviderReal {
+		t.Fatalf(`The defaults were not set.. Got %+v`, login)
+	}
+
+	if api := cfg.Limiters[1]; api.Key.Header != "X-API-Key" || len(api.Deny) != 1 {
+		t.Fatalf(`The limiter was not read properly.. Got %+v`, api)
+	}
+}
+
+func TestRead_json(t *testing.T) {
+	cfg, err := Read(strings.NewReader(`{
+		"backend": {"type": "redis", "addr": "localhost:6379"},
+		"limiters": [{"name": "login", "limit": 5, "interval": "30s"}]
+	}`))
+
+	if err != nil {
+		t.Fatalf(`An error occurred while reading the configuration.. %v`, err)
+	}
+
+	if cfg.Backend.Type != BackendRedis || time.Duration(cfg.Limiters[0].Interval) != time.Second*30 {
+		t.Fatalf(`The configuration was not read properly.. Got %+v`, cfg)
+	}
+}
+
+func TestRead_invalid(t *testing.T) {
+	_, err := Read(strings.NewReader(`
+backend:
+  type: redis
+limiters:
+  - name: login
+    algorithm: leaky
+    limit: 0
+    interval: 1m
+    key:
+      source: header
+    ip:
+      provider: remote
+      trusted_proxies: [10.0.0.0/8]
+    routes:
+      - path: login
+      - path: /export
+        cost: -1
+    deny: [oops]
+  - name: login
+    limit: 1
+    interval: -1s
+  - name: billing
+    algorithm: quota
+    limit: 100
+    interval: 1h
+    period: year
+`))
+
+	v, ok := err.(*ValidationError)
+
+	if !ok {
+		t.Fatalf(`Expected a validation error.. Got %v`, err)
+	}
+
+	expected := []string{
+		"backend.addr",
+		"limiters[0].algorithm",
+		"limiters[0].limit",
+		"limiters[0].key.header",
+		"limiters[0].ip.trusted_proxies",
+		"limiters[0].routes[0].path",
+		"limiters[0].routes[1].cost",
+		"limiters[0].deny[0]",
+		"limiters[1].name",
+		"limiters[1].interval",
+		"limiters[2].interval",
+		"limiters[2].period",
+	}
+
+	if len(v.Problems) != len(expected) {
+		t.Fatalf(`Problems differ.. Expected %v.. Got %v`, expected, v.Problems)
+	}
+
+	for i, field := range expected {
+		if !strings.HasPrefix(v.Problems[i], field+": ") {
+			t.Fatalf(`Problems differ.. Expected %s.. Got %s`, field, v.Problems[i])
+		}
+	}
+}
+
+func TestRead_malformed(t *testing.T) {
+	cases := []string{
+		"limiters: [{name: login, limit: 5, interval: 1m, oops: true}]",
+		"limiters: [{name: login, limit: 5, interval: 60}]",
+		"limiters: {",
+	}
+
+	for _, v := range cases {
+		if _, err := Read(strings.NewReader(v)); err == nil {
+			t.Fatalf(`Expected an error for %s`, v)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gottle.yaml")
+
+	if err := os.WriteFile(path, []byte(sample), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Load(path); err != nil {
+		t.Fatalf(`An error occurred while loading the configuration.. %v`, err)
+	}
+
+	if _, err := Load(path + ".missing"); err == nil {
+		t.Fatal(`Expected an error for a missing file`)
+	}
+}
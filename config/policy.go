@@ -0,0 +1,352 @@
+package config
+
+import (
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/adelowo/gottle"
+	"github.com/adelowo/gottle/memcached"
+	"github.com/adelowo/gottle/persistent"
+	"github.com/adelowo/gottle/redis"
+	"github.com/adelowo/gottle/sharded"
+	"github.com/adelowo/onecache"
+	"github.com/adelowo/onecache/memory"
+	"github.com/bradfitz/gomemcache/memcache"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+//Policy is the set of throttlers built from a Config
+type Policy struct {
+	limiters []*limiter
+
+	//The backend, handed over to the next policy when it is unchanged
+	backend Backend
+	stores  func(Limiter) onecache.Store
+	closers []func() error
+
+	//The requests a Reloader is serving with the policy, which is
+	//only closed once it has been replaced and they are all done
+	refs    atomic.Int64
+	retired atomic.Bool
+	closing sync.Once
+}
+
+//limiter is a throttler along with the requests it applies to
+type limiter struct {
+	name      string
+	throttler *gottle.OnecacheThrottler
+	ip        gottle.IPProvider
+	routes    []Route
+	allow     []*net.IPNet
+	deny      []*net.IPNet
+	shadow    bool
+}
+
+//Build creates the backend and a throttler for every limiter.
+//opts are applied to every throttler, before the configuration,
+//which makes them the place for metrics, tracing and logging
+func (c *Config) Build(opts ...gottle.Option) (*Policy, error) {
+	return c.build(nil, opts)
+}
+
+//build works like Build but reuses the backend of previous if it is the same.
+//The backend is then owned by the new policy
+func (c *Config) build(previous *Policy, opts []gottle.Option) (*Policy, error) {
+	//Configurations written in code rather than read get the defaults too
+	c.setDefaults()
+
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+
+	p := &Policy{backend: c.Backend}
+
+	reused := previous != nil && previous.backend == c.Backend
+
+	if reused {
+		p.stores = previous.stores
+	} else {
+		stores, err := p.open(c.Backend)
+
+		if err != nil {
+			return nil, err
+		}
+
+		p.stores = stores
+	}
+
+	for _, l := range c.Limiters {
+		built, err := l.build(p.stores(l), opts)
+
+		if err != nil {
+			p.Close()
+			return nil, err
+		}
+
+		p.limiters = append(p.limiters, built)
+	}
+
+	if reused {
+		p.closers, previous.closers = previous.closers, nil
+	}
+
+	return p, nil
+}
+
+//open returns a function that gives each limiter it's store.
+//Every limiter shares the same one, but for memcached which counts in
+//windows as long as the limiter's interval
+func (p *Policy) open(b Backend) (func(Limiter) onecache.Store, error) {
+	switch b.Type {
+	case BackendSharded:
+		store := sharded.New()
+		p.closers = append(p.closers, func() error { store.Close(); return nil })
+
+		return func(Limiter) onecache.Store { return store }, nil
+
+	case BackendRedis:
+		client := goredis.NewClient(&goredis.Options{Addr: b.Addr})
+		p.closers = append(p.closers, client.Close)
+
+		var opts []redis.Option
+
+		if b.Prefix != "" {
+			opts = append(opts, redis.Prefix(b.Prefix))
+		}
+
+		store := redis.New(client, opts...)
+
+		return func(Limiter) onecache.Store { return store }, nil
+
+	case BackendMemcached:
+		client := memcache.New(b.Addr)
+
+		var opts []memcached.Option
+
+		if b.Prefix != "" {
+			opts = append(opts, memcached.Prefix(b.Prefix))
+		}
+
+		return func(l Limiter) onecache.Store {
+			return memcached.New(client, time.Duration(l.Interval), opts...)
+		}, nil
+
+	case BackendPersistent:
+		store, err := persistent.New(b.Path)
+
+		if err != nil {
+			return nil, err
+		}
+
+		p.closers = append(p.closers, store.Close)
+
+		return func(Limiter) onecache.Store { return store }, nil
+	}
+
+	store := memory.New()
+
+	return func(Limiter) onecache.Store { return store }, nil
+}
+
+func (l Limiter) build(store onecache.Store, opts []gottle.Option) (*limiter, error) {
+	built := &limiter{name: l.Name, routes: l.Routes, shadow: l.Shadow}
+
+	var err error
+
+	switch l.IP.Provider {
+	case IPProviderRemote:
+		built.ip = gottle.NewRemoteIP()
+	case IPProviderTrusted:
+		if built.ip, err = gottle.NewTrustedProxies(l.IP.TrustedProxies...); err != nil {
+			return nil, err
+		}
+	default:
+		built.ip = gottle.NewRealIP()
+	}
+
+	if built.allow, err = gottle.ParseNetworks(l.Allow...); err != nil {
+		return nil, err
+	}
+
+	if built.deny, err = gottle.ParseNetworks(l.Deny...); err != nil {
+		return nil, err
+	}
+
+	var keys gottle.IPProvider = built.ip
+
+	if l.Key.Source == KeySourceHeader {
+		keys = headerKey{header: l.Key.Header, fallback: built.ip}
+	}
+
+	//Limiters share the store, their keys are kept apart by name
+	prefix := l.Name + ":"
+
+	throttlerOpts := append(append([]gottle.Option{}, opts...),
+		gottle.Name(l.Name),
+		gottle.Store(store),
+		gottle.IP(keys),
+		gottle.KeyGenerator(func(key string) string { return prefix + key }),
+		gottle.ThrottleCondition(time.Duration(l.Interval), l.Limit),
+		gottle.Shadow(l.Shadow))
+
+	if l.Algorithm == AlgorithmQuota {
+		loc, err := time.LoadLocation(l.Location)
+
+		if err != nil {
+			return nil, err
+		}
+
+		throttlerOpts = append(throttlerOpts, gottle.Quota(periods[l.Period], loc))
+	}
+
+	built.throttler = gottle.NewOneCacheThrottler(throttlerOpts...)
+
+	return built, nil
+}
+
+//headerKey identifies clients by a header, falling back to their IP
+type headerKey struct {
+	header   string
+	fallback gottle.IPProvider
+}
+
+func (h headerKey) IP(r *http.Request) string {
+	if key := r.Header.Get(h.header); key != "" {
+		return key
+	}
+
+	return h.fallback.IP(r)
+}
+
+//match checks if the limiter applies to r and returns what r costs.
+//Requests matching several routes cost what the first one says
+func (l *limiter) match(r *http.Request) (int, bool) {
+	if len(l.routes) == 0 {
+		return 1, true
+	}
+
+	for _, route := range l.routes {
+		if route.matches(r) {
+			return route.Cost, true
+		}
+	}
+
+	return 0, false
+}
+
+func (route Route) matches(r *http.Request) bool {
+	if prefix, ok := strings.CutSuffix(route.Path, "*"); ok {
+		if !strings.HasPrefix(r.URL.Path, prefix) {
+			return false
+		}
+	} else if r.URL.Path != route.Path {
+		return false
+	}
+
+	if len(route.Methods) == 0 {
+		return true
+	}
+
+	for _, method := range route.Methods {
+		if strings.EqualFold(method, r.Method) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func contains(nets []*net.IPNet, addr string) bool {
+	ip := net.ParseIP(addr)
+
+	if ip == nil {
+		return false
+	}
+
+	for _, n := range nets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+//Throttler returns the throttler of the limiter called name, or nil if there is none
+func (p *Policy) Throttler(name string) *gottle.OnecacheThrottler {
+	for _, l := range p.limiters {
+		if l.name == name {
+			return l.throttler
+		}
+	}
+
+	return nil
+}
+
+//Middleware wraps next so every request goes through the limiters whose
+//routes it matches, in the order they were configured. Clients on a
+//limiter's deny list get a 403 Forbidden, unless it is in shadow mode,
+//and the ones on it's allow list skip it. Rate limited clients get a
+//429 Too Many Requests, requests that can't be throttled because of
+//a store error are let through
+func (p *Policy) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p.serve(w, r, next)
+	})
+}
+
+func (p *Policy) serve(w http.ResponseWriter, r *http.Request, next http.Handler) {
+	if p.admit(w, r) {
+		next.ServeHTTP(w, r)
+	}
+}
+
+//admit runs r through the limiters and reports if it may go ahead.
+//Requests that may not go ahead have been answered
+func (p *Policy) admit(w http.ResponseWriter, r *http.Request) bool {
+	for _, l := range p.limiters {
+		cost, ok := l.match(r)
+
+		if !ok {
+			continue
+		}
+
+		ip := l.ip.IP(r)
+
+		//Limiters in shadow mode never reject a request
+		if contains(l.deny, ip) && !l.shadow {
+			http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+			return false
+		}
+
+		if contains(l.allow, ip) {
+			continue
+		}
+
+		if err := l.throttler.ThrottleN(r, cost); err == gottle.ErrClientIsRateLimited {
+			http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+			return false
+		}
+	}
+
+	return true
+}
+
+//Close releases the backend
+func (p *Policy) Close() error {
+	var first error
+
+	for _, closer := range p.closers {
+		if err := closer(); err != nil && first == nil {
+			first = err
+		}
+	}
+
+	p.closers = nil
+
+	return first
+}
@@ -0,0 +1,220 @@
+package config
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func build(t *testing.T, src string) *Policy {
+	cfg, err := Read(strings.NewReader(src))
+
+	if err != nil {
+		t.Fatalf(`An error occurred while reading the configuration.. %v`, err)
+	}
+
+	p, err := cfg.Build()
+
+	if err != nil {
+		t.Fatalf(`An error occurred while building the policy.. %v`, err)
+	}
+
+	t.Cleanup(func() { p.Close() })
+
+	return p
+}
+
+func serve(h http.Handler, method, path string, headers map[string]string) int {
+	r := httptest.NewRequest(method, path, nil)
+	r.RemoteAddr = "10.0.0.1:1234"
+
+	for k, v := range headers {
+		r.Header.Set(k, v)
+	}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	return w.Code
+}
+
+var ok = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusNoContent)
+})
+
+func TestPolicy_Middleware(t *testing.T) {
+	h := build(t, sample).Middleware(ok)
+
+	client := map[string]string{"X-Forwarded-For": "5.6.7.8"}
+
+	cases := []struct {
+		method, path string
+		headers      map[string]string
+		expected     int
+	}{
+		//Only POST /login is limited by login
+		{http.MethodGet, "/login", client, http.StatusNoContent},
+		{http.MethodGet, "/login", client, http.StatusNoContent},
+		{http.MethodGet, "/login", client, http.StatusNoContent},
+		{http.MethodGet, "/login", client, http.StatusNoContent},
+		{http.MethodGet, "/login", client, http.StatusNoContent},
+		{http.MethodGet, "/login", client, http.StatusNoContent},
+		{http.MethodPost, "/login", client, http.StatusNoContent},
+		{http.MethodPost, "/login", client, http.StatusNoContent},
+		{http.MethodPost, "/login", client, http.StatusNoContent},
+		{http.MethodPost, "/login", client, http.StatusNoContent},
+		{http.MethodPost, "/login", client, http.StatusNoContent},
+		{http.MethodPost, "/login", client, http.StatusTooManyRequests},
+
+		{http.MethodGet, "/api/users", map[string]string{"X-Forwarded-For": "1.2.3.4"}, http.StatusForbidden},
+		{http.MethodGet, "/api/users", map[string]string{"X-Forwarded-For": "127.0.0.1"}, http.StatusNoContent},
+		{http.MethodGet, "/other", map[string]string{"X-Forwarded-For": "1.2.3.4"}, http.StatusNoContent},
+	}
+
+	for i, v := range cases {
+		if code := serve(h, v.method, v.path, v.headers); code != v.expected {
+			t.Fatalf(`Status codes differ for request %d, %s %s.. Expected %d.. Got %d`,
+				i+1, v.method, v.path, v.expected, code)
+		}
+	}
+}
+
+func TestPolicy_headerKey(t *testing.T) {
+	p := build(t, `
+limiters:
+  - name: api
+    limit: 1
+    interval: 1h
+    key:
+      source: header
+      header: X-API-Key
+`)
+
+	h := p.Middleware(ok)
+
+	if code := serve(h, http.MethodGet, "/", map[string]string{"X-API-Key": "one"}); code != http.StatusNoContent {
+		t.Fatalf(`Status codes differ.. Expected %d.. Got %d`, http.StatusNoContent, code)
+	}
+
+	//Another key has it's own limit
+	if code := serve(h, http.MethodGet, "/", map[string]string{"X-API-Key": "two"}); code != http.StatusNoContent {
+		t.Fatalf(`Status codes differ.. Expected %d.. Got %d`, http.StatusNoContent, code)
+	}
+
+	status, err := p.Throttler("api").Status("api:one")
+
+	if err != nil || status.Hits != 1 {
+		t.Fatalf(`Expected the hits to be kept under the limiter's name.. Got %+v, %v`, status, err)
+	}
+
+	if p.Throttler("oops") != nil {
+		t.Fatal(`Expected no throttler for an unknown limiter`)
+	}
+}
+
+func TestPolicy_cost(t *testing.T) {
+	h := build(t, `
+limiters:
+  - name: api
+    limit: 10
+    interval: 1h
+    routes:
+      - path: /api/export
+        cost: 8
+      - path: /api/*
+`).Middleware(ok)
+
+	client := map[string]string{"X-Forwarded-For": "5.6.7.8"}
+
+	cases := []struct {
+		path     string
+		expected int
+	}{
+		{"/api/export", http.StatusNoContent},
+		{"/api/export", http.StatusTooManyRequests},
+		{"/api/users", http.StatusNoContent},
+		{"/api/users", http.StatusNoContent},
+		{"/api/users", http.StatusTooManyRequests},
+	}
+
+	for i, v := range cases {
+		if code := serve(h, http.MethodGet, v.path, client); code != v.expected {
+			t.Fatalf(`Status codes differ for request %d, %s.. Expected %d.. Got %d`,
+				i+1, v.path, v.expected, code)
+		}
+	}
+}
+
+func TestPolicy_quota(t *testing.T) {
+	p := build(t, `
+limiters:
+  - name: billing
+    algorithm: quota
+    limit: 2
+    period: month
+`)
+
+	h := p.Middleware(ok)
+
+	client := map[string]string{"X-Forwarded-For": "5.6.7.8"}
+
+	for i, expected := range []int{http.StatusNoContent, http.StatusNoContent, http.StatusTooManyRequests} {
+		if code := serve(h, http.MethodGet, "/", client); code != expected {
+			t.Fatalf(`Status codes differ for request %d.. Expected %d.. Got %d`, i+1, expected, code)
+		}
+	}
+
+	usage, err := p.Throttler("billing").Usage("billing:5.6.7.8")
+
+	if err != nil {
+		t.Fatalf(`An error occurred while fetching the usage.. %v`, err)
+	}
+
+	if usage.Used != 2 || usage.ResetAt.Day() != 1 || usage.ResetAt.Location() != time.UTC {
+		t.Fatalf(`Expected the quota to reset on the 1st UTC.. Got %+v`, usage)
+	}
+}
+
+func TestPolicy_shadow(t *testing.T) {
+	h := build(t, `
+limiters:
+  - name: api
+    limit: 1
+    interval: 1h
+    deny: [5.6.7.8]
+    shadow: true
+`).Middleware(ok)
+
+	client := map[string]string{"X-Forwarded-For": "5.6.7.8"}
+
+	for i := 0; i < 3; i++ {
+		if code := serve(h, http.MethodGet, "/", client); code != http.StatusNoContent {
+			t.Fatalf(`Limiters in shadow mode are not supposed to reject requests.. Got %d`, code)
+		}
+	}
+}
+
+func TestConfig_Build_redis(t *testing.T) {
+	mr := miniredis.RunT(t)
+
+	p := build(t, `
+backend:
+  type: redis
+  addr: `+mr.Addr()+`
+  prefix: "app:"
+limiters:
+  - name: login
+    limit: 1
+    interval: 1m
+`)
+
+	serve(p.Middleware(ok), http.MethodGet, "/", map[string]string{"X-Forwarded-For": "5.6.7.8"})
+
+	if !mr.Exists("app:login:5.6.7.8") {
+		t.Fatalf(`Expected the hits to be kept in Redis.. Got %v`, mr.Keys())
+	}
+}
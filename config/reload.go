@@ -0,0 +1,200 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/adelowo/gottle"
+)
+
+//Reloader serves requests with the most recent Policy built from a
+//configuration, so limits, routes and lists can be changed without a restart.
+//
+//A request goes through a single policy from start to end, even if another
+//one is swapped in while it is being served. Counters are kept across
+//reloads as long as the backend, the limiter's name and it's key source
+//stay the same, since the keys then stay the same
+type Reloader struct {
+	opts []gottle.Option
+
+	mu       sync.Mutex //serializes reloads
+	onReload []func(*Policy)
+	current  atomic.Pointer[Policy]
+}
+
+//NewReloader builds the first policy from cfg.
+//opts are applied to the throttlers of every policy, see Config.Build
+func NewReloader(cfg *Config, opts ...gottle.Option) (*Reloader, error) {
+	p, err := cfg.Build(opts...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	r := &Reloader{opts: opts}
+	r.current.Store(p)
+
+	return r, nil
+}
+
+//Policy returns the policy currently in use
+func (r *Reloader) Policy() *Policy {
+	return r.current.Load()
+}
+
+//OnReload registers fn to be called with every new policy before it is
+//put in use, which is where hooks should be registered on it's throttlers.
+//fn is also called with the current policy
+func (r *Reloader) OnReload(fn func(*Policy)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.onReload = append(r.onReload, fn)
+
+	fn(r.current.Load())
+}
+
+//Reload builds a policy from cfg and swaps it in. If cfg can't be built,
+//the current policy stays in use. A backend that is no longer used is
+//closed once the requests still going through the previous policy are
+//done with it. The error closing it is only returned if that is right away
+func (r *Reloader) Reload(cfg *Config) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	previous := r.current.Load()
+
+	p, err := cfg.build(previous, r.opts)
+
+	if err != nil {
+		return err
+	}
+
+	for _, fn := range r.onReload {
+		fn(p)
+	}
+
+	r.current.Store(p)
+
+	//A no-op if the backend was handed over
+	return previous.retire()
+}
+
+//acquire returns the current policy, which is not closed until it is released
+func (r *Reloader) acquire() *Policy {
+	for {
+		p := r.current.Load()
+		p.refs.Add(1)
+
+		//The policy may have been retired before it was counted
+		if r.current.Load() == p {
+			return p
+		}
+
+		p.release()
+	}
+}
+
+//release lets go of p, closing it if it has been replaced
+//and this was the last request using it
+func (p *Policy) release() {
+	if p.refs.Add(-1) == 0 && p.retired.Load() {
+		p.closeRetired()
+	}
+}
+
+//retire closes p once no request is using it anymore
+func (p *Policy) retire() error {
+	p.retired.Store(true)
+
+	if p.refs.Load() == 0 {
+		return p.closeRetired()
+	}
+
+	return nil
+}
+
+func (p *Policy) closeRetired() error {
+	var err error
+
+	p.closing.Do(func() {
+		err = p.Close()
+	})
+
+	return err
+}
+
+//Watch reloads the configuration file at path whenever it's content
+//changes, checking every interval until ctx is done. Errors reading or
+//building the file are passed to onError, which may be nil, and the
+//current policy is kept until the file is fixed
+func (r *Reloader) Watch(ctx context.Context, path string, interval time.Duration, onError func(error)) {
+	if onError == nil {
+		onError = func(error) {}
+	}
+
+	//The file is loaded on the first check as well, it may have
+	//changed since the current policy was built. Counters are kept
+	var last []byte
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		buf, err := os.ReadFile(path)
+
+		if err != nil {
+			onError(err)
+			continue
+		}
+
+		if bytes.Equal(buf, last) {
+			continue
+		}
+
+		last = buf
+
+		cfg, err := Read(bytes.NewReader(buf))
+
+		if err == nil {
+			err = r.Reload(cfg)
+		}
+
+		if err != nil {
+			onError(err)
+		}
+	}
+}
+
+//Middleware works like Policy.Middleware, with whichever policy
+//is in use when a request comes in
+func (r *Reloader) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		p := r.acquire()
+		admitted := p.admit(w, req)
+		p.release()
+
+		if admitted {
+			next.ServeHTTP(w, req)
+		}
+	})
+}
+
+//Close releases the backend of the current policy
+func (r *Reloader) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.current.Load().Close()
+}
@@ -0,0 +1,187 @@
+package config
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/adelowo/gottle"
+)
+
+const reloadSample = `
+limiters:
+  - name: login
+    limit: 2
+    interval: 1m
+`
+
+func read(t *testing.T, src string) *Config {
+	cfg, err := Read(strings.NewReader(src))
+
+	if err != nil {
+		t.Fatalf(`An error occurred while reading the configuration.. %v`, err)
+	}
+
+	return cfg
+}
+
+func TestReloader_Reload(t *testing.T) {
+	r, err := NewReloader(read(t, reloadSample))
+
+	if err != nil {
+		t.Fatalf(`An error occurred while building the policy.. %v`, err)
+	}
+
+	defer r.Close()
+
+	var limited []gottle.Event
+
+	r.OnReload(func(p *Policy) {
+		p.Throttler("login").OnLimited(func(e gottle.Event) { limited = append(limited, e) })
+	})
+
+	h := r.Middleware(ok)
+	client := map[string]string{"X-Forwarded-For": "5.6.7.8"}
+
+	for i := 0; i < 2; i++ {
+		serve(h, http.MethodGet, "/", client)
+	}
+
+	if code := serve(h, http.MethodGet, "/", client); code != http.StatusTooManyRequests {
+		t.Fatalf(`Status codes differ.. Expected %d.. Got %d`, http.StatusTooManyRequests, code)
+	}
+
+	if err := r.Reload(read(t, strings.Replace(reloadSample, "limit: 2", "limit: 3", 1))); err != nil {
+		t.Fatalf(`An error occurred while reloading the policy.. %v`, err)
+	}
+
+	//The hits are kept, the client has one more attempt under the new limit
+	if code := serve(h, http.MethodGet, "/", client); code != http.StatusNoContent {
+		t.Fatalf(`Status codes differ.. Expected %d.. Got %d`, http.StatusNoContent, code)
+	}
+
+	if code := serve(h, http.MethodGet, "/", client); code != http.StatusTooManyRequests {
+		t.Fatalf(`Status codes differ.. Expected %d.. Got %d`, http.StatusTooManyRequests, code)
+	}
+
+	if len(limited) != 2 || limited[1].Limit != 3 {
+		t.Fatalf(`Expected the hooks to be registered on the new policy.. Got %+v`, limited)
+	}
+}
+
+func TestReloader_Reload_invalid(t *testing.T) {
+	r, err := NewReloader(read(t, reloadSample))
+
+	if err != nil {
+		t.Fatalf(`An error occurred while building the policy.. %v`, err)
+	}
+
+	defer r.Close()
+
+	current := r.Policy()
+
+	if err := r.Reload(&Config{}); err == nil {
+		t.Fatal(`Expected an error for an invalid configuration`)
+	}
+
+	if r.Policy() != current {
+		t.Fatal(`The current policy is supposed to be kept`)
+	}
+}
+
+func TestReloader_Watch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gottle.yaml")
+
+	if err := os.WriteFile(path, []byte(reloadSample), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := Load(path)
+
+	if err != nil {
+		t.Fatalf(`An error occurred while loading the configuration.. %v`, err)
+	}
+
+	r, err := NewReloader(cfg)
+
+	if err != nil {
+		t.Fatalf(`An error occurred while building the policy.. %v`, err)
+	}
+
+	defer r.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	errs := make(chan error, 10)
+	done := make(chan struct{})
+
+	go func() {
+		r.Watch(ctx, path, time.Millisecond*5, func(err error) { errs <- err })
+		close(done)
+	}()
+
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	os.WriteFile(path, []byte("limiters: []"), 0600)
+
+	select {
+	case <-errs:
+	case <-time.After(time.Second):
+		t.Fatal(`Expected the invalid file to be reported`)
+	}
+
+	os.WriteFile(path, []byte(strings.Replace(reloadSample, "limit: 2", "limit: 7", 1)), 0600)
+
+	deadline := time.Now().Add(time.Second)
+
+	for time.Now().Before(deadline) {
+		if status, _ := r.Policy().Throttler("login").Status("key"); status.Limit == 7 {
+			return
+		}
+
+		time.Sleep(time.Millisecond * 5)
+	}
+
+	t.Fatal(`Expected the new configuration to have been loaded`)
+}
+
+func TestReloader_Reload_inFlight(t *testing.T) {
+	r, err := NewReloader(read(t, reloadSample+"backend:\n  type: sharded\n"))
+
+	if err != nil {
+		t.Fatalf(`An error occurred while building the policy.. %v`, err)
+	}
+
+	defer r.Close()
+
+	//A request still going through the policy when it is replaced
+	previous := r.acquire()
+
+	if err := r.Reload(read(t, reloadSample)); err != nil {
+		t.Fatalf(`An error occurred while reloading the policy.. %v`, err)
+	}
+
+	if previous.closers == nil {
+		t.Fatal(`The backend is not supposed to be closed while it is in use`)
+	}
+
+	previous.release()
+
+	if previous.closers != nil {
+		t.Fatal(`Expected the backend to be closed once it is no longer in use`)
+	}
+
+	//Requests are served with the new policy
+	if p := r.acquire(); p == previous {
+		t.Fatal(`Expected the new policy to be in use`)
+	} else {
+		p.release()
+	}
+}
@@ -0,0 +1,252 @@
+package gottle
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/adelowo/onecache"
+)
+
+//Counter is an optional interface a store can implement to keep the
+//hits of a client natively, rather than as a gob encoded item that
+//has to be read, modified and written back on every throttle.
+//OnecacheThrottler makes use of it whenever the configured store implements it
+type Counter interface {
+	//Incr adds n hits to key and returns the updated number of hits.
+	//now is recorded as the most recent hit and the key
+	//should expire ttl after the last time it was incremented
+	Incr(key string, n int, now time.Time, ttl time.Duration) (int, error)
+
+	//Count returns the hits and most recent hit recorded for key.
+	//onecache.ErrCacheMiss is returned if key does not exist or has expired
+	Count(key string) (int, time.Time, error)
+}
+
+//LimitCounter is an optional interface for stores that can check a key
+//against it's limit and record the hits in one atomic operation.
+//Throttle prefers it over Counter, which needs a read and a write
+//that other throttlers sharing the store could interleave with
+type LimitCounter interface {
+	//IncrUnder adds n hits to key unless they would take it past
+	//limit, with the most recent hit no older than ttl.
+	//It returns the hits recorded for key and if the hits were added
+	IncrUnder(key string, n, limit int, now time.Time, ttl time.Duration) (int, bool, error)
+}
+
+//Decrementer is an optional interface for stores that can take hits back
+//from a key in one atomic operation. Cancelling a Reservation prefers it
+//over reading the hits and writing them back, which loses the hits others
+//record in between
+type Decrementer interface {
+	//Decr takes n hits back from key, without going below zero or
+	//changing it's most recent hit and expiry, and returns the hits left.
+	//Keys that don't exist are left alone
+	Decr(key string, n int) (int, error)
+}
+
+//WindowedStore is implemented by stores that count hits in fixed windows
+//of their own, ignoring the ttl they are given, like the memcached one.
+//Calendar quotas can't be kept in them
+type WindowedStore interface {
+	//Window returns how long the store's windows are
+	Window() time.Duration
+}
+
+//client identifies who an operation on the store is carried out for
+//and the limits that apply to it
+type client struct {
+	ctx context.Context
+	ip  string
+	key string
+
+	limit    int
+	interval time.Duration
+
+	//window is the start of the calendar window the hits of c are counted
+	//in and end is when it ends, for throttlers in quota mode
+	window string
+	end    time.Time
+}
+
+//entry returns the key the hits of c are stored under
+func (c client) entry() string {
+	if c.window == "" {
+		return c.key
+	}
+
+	return c.key + windowSeparator + c.window
+}
+
+//resetAt returns when hits whose most recent one is last stop counting against c
+func (c client) resetAt(last time.Time) time.Time {
+	if !c.end.IsZero() {
+		return c.end
+	}
+
+	return last.Add(c.interval)
+}
+
+//clientOf returns the client making r
+func (t *OnecacheThrottler) clientOf(r *http.Request) client {
+	ip := t.ipProvider.IP(r)
+
+	c := client{ctx: r.Context(), ip: ip, key: t.keyGenerator(ip)}
+	c.limit, c.interval = t.limitsFor(c)
+	t.windowed(&c)
+
+	return c
+}
+
+//throttle records n hits for c unless they would take it past it's limit.
+//It returns the hits recorded for c, with or without the new ones
+func (t *OnecacheThrottler) throttle(c client, n int) (int, error) {
+	if counter, ok := t.store.(LimitCounter); ok {
+		var hits int
+		var added bool
+
+		err := t.timed(c, OpIncrUnder, func() (err error) {
+			hits, added, err = counter.IncrUnder(c.entry(), n, c.limit, t.now(), c.interval)
+			return err
+		})
+
+		if err != nil {
+			return 0, err
+		}
+
+		if !added {
+			return hits, ErrClientIsRateLimited
+		}
+
+		return hits, nil
+	}
+
+	item, ok, err := t.load(c)
+
+	//No amount of waiting lets more hits than the limit through
+	if err == nil && !ok && n > c.limit {
+		return 0, ErrClientIsRateLimited
+	}
+
+	if err == nil && ok && t.exceeds(c, item, n) {
+		return item.Hits, ErrClientIsRateLimited
+	}
+
+	return t.incr(c, n)
+}
+
+//limited checks if item, the state of c, has reached the maximum
+//number of tries within the interval
+func (t *OnecacheThrottler) limited(c client, item *throttledItem) bool {
+	return t.exceeds(c, item, defaultThrottledItemIncrement)
+}
+
+//exceeds checks if n more hits would take item, the state of c,
+//past the maximum number of tries within the interval
+func (t *OnecacheThrottler) exceeds(c client, item *throttledItem, n int) bool {
+	return item.Hits+n > c.limit &&
+		t.now().Sub(item.LastThrottledAt) <= c.interval
+}
+
+//load fetches the item stored for c.
+//ok is false if the client has not been throttled
+func (t *OnecacheThrottler) load(c client) (*throttledItem, bool, error) {
+	if counter, ok := t.store.(Counter); ok {
+		var hits int
+		var last time.Time
+
+		err := t.timed(c, OpCount, func() (err error) {
+			hits, last, err = counter.Count(c.entry())
+			return err
+		})
+
+		if err == onecache.ErrCacheMiss {
+			return nil, false, nil
+		}
+
+		if err != nil {
+			return nil, false, err
+		}
+
+		return &throttledItem{Hits: hits, LastThrottledAt: last}, true, nil
+	}
+
+	if !t.has(c) {
+		return nil, false, nil
+	}
+
+	var buf []byte
+
+	err := t.timed(c, OpGet, func() (err error) {
+		buf, err = t.store.Get(c.entry())
+		return err
+	})
+
+	if err != nil {
+		return nil, false, err
+	}
+
+	item := new(throttledItem)
+
+	if err := DecodeGob(buf, item); err != nil {
+		t.logDecodeError(c, err)
+		return nil, false, err
+	}
+
+	return item, true, nil
+}
+
+//has checks if the store holds anything for c
+func (t *OnecacheThrottler) has(c client) bool {
+	var has bool
+
+	t.timed(c, OpHas, func() error {
+		has = t.store.Has(c.entry())
+		return nil
+	})
+
+	return has
+}
+
+//incr records n hits for c and returns the updated number of hits
+func (t *OnecacheThrottler) incr(c client, n int) (int, error) {
+	if counter, ok := t.store.(Counter); ok {
+		var hits int
+
+		err := t.timed(c, OpIncr, func() (err error) {
+			hits, err = counter.Incr(c.entry(), n, t.now(), c.interval)
+			return err
+		})
+
+		return hits, err
+	}
+
+	item, ok, err := t.load(c)
+
+	if err != nil {
+		return 0, err
+	}
+
+	if !ok {
+		item = new(throttledItem)
+	}
+
+	item.LastThrottledAt = t.now()
+	item.Hits += n
+
+	buf, err := EncodeGob(item)
+
+	if err != nil {
+		return 0, err
+	}
+
+	err = t.timed(c, OpSet, func() error {
+		return t.store.Set(c.entry(), buf, c.interval)
+	})
+
+	if err != nil {
+		return 0, err
+	}
+
+	return item.Hits, nil
+}
@@ -0,0 +1,114 @@
+package gottle
+
+import (
+	"errors"
+	"strings"
+)
+
+//ErrEnumerationNotSupported is returned when listing or resetting keys
+//by prefix with a store that can't list the keys it holds
+var ErrEnumerationNotSupported = errors.New(
+	`gottle: The store does not support listing keys`)
+
+//Enumerator is an optional interface for stores that can list the keys they hold.
+//Stores that wrap another store and may not be able to list keys
+//should return ErrEnumerationNotSupported
+type Enumerator interface {
+	//Enumerate calls fn for every key starting with prefix,
+	//stopping early if fn returns false
+	Enumerate(prefix string, fn func(key string) bool) error
+}
+
+//enumerate returns every key starting with prefix.
+//The keys are collected before any of them is read or cleared,
+//as some stores can't take other operations while they are being listed.
+//In quota mode, only the keys of the current calendar window are returned
+func (t *OnecacheThrottler) enumerate(prefix string) ([]string, error) {
+	enumerator, ok := t.store.(Enumerator)
+
+	if !ok {
+		return nil, ErrEnumerationNotSupported
+	}
+
+	var suffix string
+
+	if t.quota != nil {
+		start, _ := t.quota.window(t.now())
+		suffix = windowSeparator + t.quota.label(start)
+	}
+
+	var keys []string
+
+	err := enumerator.Enumerate(prefix, func(key string) bool {
+		//Stored overrides are not hits, they outlive a reset
+		if strings.HasPrefix(key, overrideKeyPrefix) {
+			return true
+		}
+
+		if suffix == "" {
+			keys = append(keys, key)
+		} else if key, ok := strings.CutSuffix(key, suffix); ok {
+			keys = append(keys, key)
+		}
+
+		return true
+	})
+
+	return keys, err
+}
+
+//Keys calls fn with the status of every key starting with prefix,
+//stopping early if fn returns false. If limitedOnly is true,
+//only the keys that are currently rate limited are passed to fn
+func (t *OnecacheThrottler) Keys(prefix string, limitedOnly bool, fn func(KeyStatus) bool) error {
+	keys, err := t.enumerate(prefix)
+
+	if err != nil {
+		return err
+	}
+
+	for _, key := range keys {
+		status, err := t.Status(key)
+
+		if err != nil {
+			return err
+		}
+
+		//Expired since it was listed
+		if status.Hits == 0 {
+			continue
+		}
+
+		if limitedOnly && !status.Limited {
+			continue
+		}
+
+		if !fn(status) {
+			return nil
+		}
+	}
+
+	return nil
+}
+
+//Reset clears every key starting with prefix and returns
+//the number of keys cleared. An empty prefix clears every key
+func (t *OnecacheThrottler) Reset(prefix string) (int, error) {
+	keys, err := t.enumerate(prefix)
+
+	if err != nil {
+		return 0, err
+	}
+
+	var n int
+
+	for _, key := range keys {
+		if err := t.ClearKey(key); err != nil {
+			return n, err
+		}
+
+		n++
+	}
+
+	return n, nil
+}
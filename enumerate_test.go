@@ -0,0 +1,148 @@
+package gottle
+
+import (
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/adelowo/onecache/memory"
+)
+
+//enumeratingStore is an in memory store that keeps track of it's keys
+type enumeratingStore struct {
+	*memory.InMemoryStore
+
+	mu   sync.Mutex
+	keys map[string]bool
+}
+
+func newEnumeratingStore() *enumeratingStore {
+	return &enumeratingStore{InMemoryStore: memory.New(), keys: make(map[string]bool)}
+}
+
+func (s *enumeratingStore) Set(key string, data []byte, expires time.Duration) error {
+	s.mu.Lock()
+	s.keys[key] = true
+	s.mu.Unlock()
+
+	return s.InMemoryStore.Set(key, data, expires)
+}
+
+func (s *enumeratingStore) Delete(key string) error {
+	s.mu.Lock()
+	delete(s.keys, key)
+	s.mu.Unlock()
+
+	return s.InMemoryStore.Delete(key)
+}
+
+func (s *enumeratingStore) Enumerate(prefix string, fn func(key string) bool) error {
+	s.mu.Lock()
+
+	var keys []string
+
+	for key := range s.keys {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+
+	s.mu.Unlock()
+
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if !fn(key) {
+			break
+		}
+	}
+
+	return nil
+}
+
+func throttleKeys(throttler *OnecacheThrottler, hits map[string]int) {
+	for ip, n := range hits {
+		r := httptest.NewRequest("GET", "/oops", nil)
+		r.Header.Set(xForwardedFor, ip)
+
+		for i := 0; i < n; i++ {
+			throttler.Throttle(r)
+		}
+	}
+}
+
+func TestOnecacheThrottler_Keys(t *testing.T) {
+	throttler := NewOneCacheThrottler(
+		Store(newEnumeratingStore()),
+		ThrottleCondition(time.Minute, 2))
+
+	throttleKeys(throttler, map[string]int{"10.0.0.1": 2, "10.0.0.2": 1, "192.168.0.1": 3})
+
+	cases := []struct {
+		prefix      string
+		limitedOnly bool
+		expected    []string
+	}{
+		{"", false, []string{"10.0.0.1", "10.0.0.2", "192.168.0.1"}},
+		{"10.", false, []string{"10.0.0.1", "10.0.0.2"}},
+		{"", true, []string{"10.0.0.1", "192.168.0.1"}},
+		{"10.", true, []string{"10.0.0.1"}},
+	}
+
+	for _, v := range cases {
+		var keys []string
+
+		err := throttler.Keys(v.prefix, v.limitedOnly, func(status KeyStatus) bool {
+			keys = append(keys, status.Key)
+			return true
+		})
+
+		if err != nil {
+			t.Fatalf(`An error occurred while listing the keys.. %v`, err)
+		}
+
+		if strings.Join(keys, ",") != strings.Join(v.expected, ",") {
+			t.Fatalf(`Keys differ for (%q, %v).. Expected %v.. Got %v`,
+				v.prefix, v.limitedOnly, v.expected, keys)
+		}
+	}
+}
+
+func TestOnecacheThrottler_Reset(t *testing.T) {
+	throttler := NewOneCacheThrottler(
+		Store(newEnumeratingStore()),
+		ThrottleCondition(time.Minute, 2))
+
+	throttleKeys(throttler, map[string]int{"10.0.0.1": 2, "10.0.0.2": 2, "192.168.0.1": 2})
+
+	n, err := throttler.Reset("10.")
+
+	if err != nil || n != 2 {
+		t.Fatalf(`Cleared keys differ.. Expected %d.. Got %d, %v`, 2, n, err)
+	}
+
+	for ip, limited := range map[string]bool{"10.0.0.1": false, "10.0.0.2": false, "192.168.0.1": true} {
+		status, _ := throttler.Status(ip)
+
+		if status.Limited != limited {
+			t.Fatalf(`Expected %s to be limited: %v.. Got %v`, ip, limited, status.Limited)
+		}
+	}
+}
+
+func TestOnecacheThrottler_Keys_notSupported(t *testing.T) {
+	throttler := NewOneCacheThrottler()
+
+	err := throttler.Keys("", false, func(KeyStatus) bool { return true })
+
+	if err != ErrEnumerationNotSupported {
+		t.Fatalf(`Errors differ.. Expected %v.. Got %v`, ErrEnumerationNotSupported, err)
+	}
+
+	if _, err := throttler.Reset(""); err != ErrEnumerationNotSupported {
+		t.Fatalf(`Errors differ.. Expected %v.. Got %v`, ErrEnumerationNotSupported, err)
+	}
+}
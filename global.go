@@ -0,0 +1,220 @@
+package gottle
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+//Keys of a Global, after it's name
+const (
+	globalClientPrefix = ":client:"
+	globalActiveKey    = ":active"
+)
+
+//Global is a single budget shared by every client, such as the requests
+//a database heavy endpoint can take per second. Hits are counted in fixed
+//windows of the interval, kept in the store like any other throttler's.
+//
+//So a single client can't use up the whole budget, each only gets a fair
+//share of it : the limit divided by the number of clients seen in the
+//current or previous window, whichever saw more. A request is let through
+//if it fits in both the client's share and what is left of the budget
+type Global struct {
+	t *OnecacheThrottler
+}
+
+//NewGlobal returns a Global that lets limit hits through per interval.
+//opts configure it like a throttler, such as it's store, name, IP provider
+//and reporting. Globals sharing a store need different names.
+//Candidates and quotas don't apply to it
+func NewGlobal(limit int, interval time.Duration, opts ...Option) *Global {
+	t := NewOneCacheThrottler(append(append([]Option{}, opts...),
+		ThrottleCondition(interval, limit))...)
+
+	t.quota = &quota{every: interval}
+	t.candidate = nil
+
+	return &Global{t: t}
+}
+
+//Throttle throttles an HTTP request against the budget
+func (g *Global) Throttle(r *http.Request) error {
+	return g.ThrottleN(r, defaultThrottledItemIncrement)
+}
+
+//ThrottleN throttles an HTTP request that costs n hits against the budget
+func (g *Global) ThrottleN(r *http.Request, n int) error {
+	if n < 1 {
+		return ErrInvalidCost
+	}
+
+	ip := g.t.ipProvider.IP(r)
+
+	_, err := g.take(r.Context(), ip, g.t.keyGenerator(ip), n)
+
+	return err
+}
+
+//ThrottleKey works like Throttle for a client identified by key.
+//Like the throttler's, the events and logs of the call have an empty IP
+func (g *Global) ThrottleKey(ctx context.Context, key string) error {
+	_, err := g.take(ctx, "", key, defaultThrottledItemIncrement)
+	return err
+}
+
+//Clear resets the share of the budget used by the client making r
+func (g *Global) Clear(r *http.Request) error {
+	return g.ClearKey(g.requestKey(r))
+}
+
+//ClearKey resets the share of the budget used by the client identified by key.
+//The hits it took from the budget are not given back
+func (g *Global) ClearKey(key string) error {
+	return g.t.clear(g.client(context.Background(), "", key))
+}
+
+//client returns the share of the budget of the client identified by key
+func (g *Global) client(ctx context.Context, ip, key string) client {
+	c := client{ctx: ctx, ip: ip, key: g.t.name + globalClientPrefix + key}
+	c.limit, c.interval = g.t.throttleCondition()
+	g.t.windowed(&c)
+
+	return c
+}
+
+//bucket returns the budget every client takes from
+func (g *Global) bucket(ctx context.Context) client {
+	c := client{ctx: ctx, key: g.t.name}
+	c.limit, c.interval = g.t.throttleCondition()
+	g.t.windowed(&c)
+
+	return c
+}
+
+//active returns the number of clients seen in the current window, or the
+//previous one if it saw more. They are counted under keys of their own
+func (g *Global) active(ctx context.Context) (current, previous client) {
+	current = client{ctx: ctx, key: g.t.name + globalActiveKey}
+	current.limit, current.interval = g.t.throttleCondition()
+	g.t.windowed(&current)
+
+	//The count is still needed as the previous one in the next window
+	current.interval *= 2
+
+	start, _ := g.t.quota.window(g.t.now())
+
+	previous = current
+	previous.window = g.t.quota.label(start.Add(-g.t.quota.every))
+
+	return current, previous
+}
+
+//share returns how many of the budget's hits c may take in the current window.
+//Clients that have not taken any yet are counted as active
+func (g *Global) share(c client) (int, bool, error) {
+	item, ok, err := g.t.load(c)
+
+	if err != nil {
+		return 0, false, err
+	}
+
+	newcomer := !ok || item.Hits == 0
+
+	current, previous := g.active(c.ctx)
+
+	counts := make([]int, 2)
+
+	for i, a := range []client{current, previous} {
+		counted, ok, err := g.t.load(a)
+
+		if err != nil {
+			return 0, false, err
+		}
+
+		if ok {
+			counts[i] = counted.Hits
+		}
+	}
+
+	if newcomer {
+		counts[0]++
+	}
+
+	active := max(counts[0], counts[1], 1)
+
+	limit, _ := g.t.throttleCondition()
+
+	return (limit + active - 1) / active, newcomer, nil
+}
+
+//take records n hits for the client identified by key, in it's share and
+//in the budget, or in neither. ErrClientIsRateLimited is returned along
+//with a reservation that is not OK if either is used up
+func (g *Global) take(ctx context.Context, ip, key string, n int) (*Reservation, error) {
+	t := g.t
+
+	c := g.client(ctx, ip, key)
+
+	share, newcomer, err := g.share(c)
+
+	var hits int
+
+	if err == nil {
+		c.limit = share
+		hits, err = t.throttle(c, n)
+	}
+
+	r := &Reservation{t: t, c: c, n: n, ok: err == nil}
+
+	if err == nil {
+		b := g.bucket(ctx)
+
+		if _, err = t.throttle(b, n); err == nil {
+			r.next = &Reservation{t: t, c: b, n: n, ok: true}
+
+			//Only once it's hits are in, or retries would each count it again
+			if newcomer {
+				current, _ := g.active(ctx)
+				t.incr(current, 1)
+			}
+		} else {
+			r.Cancel()
+			hits -= n
+		}
+	}
+
+	t.decided(c, hits, n, err)
+
+	switch {
+	case err == nil:
+		return r, nil
+
+	//Store errors have already been reported,
+	//a shadow throttler must not fail the request either
+	case t.shadow:
+		return &Reservation{t: t, c: c, ok: true}, nil
+
+	case err == ErrClientIsRateLimited:
+		_, end := t.quota.window(t.now())
+		r.delay = end.Sub(t.now())
+
+		return r, err
+	}
+
+	return nil, err
+}
+
+func (g *Global) requestKey(r *http.Request) string {
+	return g.t.requestKey(r)
+}
+
+func (g *Global) reserve(ctx context.Context, key string, n int) (*Reservation, error) {
+	r, err := g.take(ctx, "", key, n)
+
+	if err == ErrClientIsRateLimited {
+		return r, nil
+	}
+
+	return r, err
+}
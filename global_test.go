@@ -0,0 +1,130 @@
+package gottle
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/adelowo/gottle/sharded"
+)
+
+var _ WeightedThrottler = &Global{}
+var _ LevelThrottler = &Global{}
+var _ LevelThrottler = &OnecacheThrottler{}
+
+func TestGlobal_fairShare(t *testing.T) {
+	clock := &fixedClock{time.Date(2017, time.March, 1, 12, 0, 30, 0, time.UTC)}
+
+	g := NewGlobal(10, time.Minute, Clock(clock), Store(sharded.New()))
+
+	take := func(window int, key string, expected ...error) {
+		t.Helper()
+
+		for i, e := range expected {
+			if err := g.ThrottleKey(context.Background(), key); err != e {
+				t.Fatalf(`Errors differ for hit %d of %s in window %d.. Expected %v.. Got %v`,
+					i+1, key, window, e, err)
+			}
+		}
+	}
+
+	//Alone, a can take as much as it wants
+	take(1, "a", nil, nil, nil, nil, nil, nil)
+
+	//Once b shows up, they get half of the budget each
+	take(1, "b", nil, nil, nil, nil, ErrClientIsRateLimited)
+	take(1, "a", ErrClientIsRateLimited)
+
+	clock.t = clock.t.Add(time.Minute)
+
+	//Both were seen in the previous window, so a can't take it all
+	take(2, "a", nil, nil, nil, nil, nil, ErrClientIsRateLimited)
+	take(2, "b", nil, nil, nil, nil, nil)
+
+	//The budget is used up, c's share of it can't be given
+	take(2, "c", ErrClientIsRateLimited)
+}
+
+func TestGlobal_bucketDenied(t *testing.T) {
+	g := NewGlobal(2, time.Minute)
+
+	g.ThrottleKey(context.Background(), "a")
+	g.ThrottleKey(context.Background(), "b")
+
+	if err := g.ThrottleKey(context.Background(), "c"); err != ErrClientIsRateLimited {
+		t.Fatalf(`Errors differ.. Expected %v.. Got %v`, ErrClientIsRateLimited, err)
+	}
+
+	//The hit c took from it's share was given back
+	c := g.client(context.Background(), "", "c")
+
+	if item, ok, _ := g.t.load(c); ok && item.Hits != 0 {
+		t.Fatalf(`Expected c to have no hits.. Got %d`, item.Hits)
+	}
+}
+
+func TestGlobal_Hierarchy(t *testing.T) {
+	perIP := NewOneCacheThrottler(ThrottleCondition(time.Minute, 5))
+
+	h := NewHierarchy(
+		Level{Name: "ip", Throttler: perIP},
+		Level{Name: "global", Throttler: NewGlobal(1, time.Minute)},
+	)
+
+	handler := Middleware(h)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	for i, code := range []int{http.StatusNoContent, http.StatusTooManyRequests} {
+		r := httptest.NewRequest(http.MethodGet, "/oops", nil)
+		r.Header.Set(xForwardedFor, "123.456.789.000")
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+
+		if w.Code != code {
+			t.Fatalf(`Status codes differ for request %d.. Expected %d.. Got %d`, i+1, code, w.Code)
+		}
+	}
+
+	//The request the global limit denied was given back on the per IP one
+	if status, _ := perIP.Status("123.456.789.000"); status.Hits != 1 {
+		t.Fatalf(`Hits differ.. Expected %d.. Got %d`, 1, status.Hits)
+	}
+}
+
+func TestGlobal_shadow(t *testing.T) {
+	g := NewGlobal(1, time.Minute, Shadow(true))
+
+	r := httptest.NewRequest(http.MethodGet, "/oops", nil)
+	r.Header.Set(xForwardedFor, "123.456.789.000")
+
+	for i := 0; i < 3; i++ {
+		if err := g.Throttle(r); err != nil {
+			t.Fatalf(`Globals in shadow mode are not supposed to reject requests.. Got %v`, err)
+		}
+	}
+}
+
+func TestGlobal_deniedNewcomer(t *testing.T) {
+	clock := &fixedClock{time.Date(2017, time.March, 1, 12, 0, 30, 0, time.UTC)}
+
+	g := NewGlobal(4, time.Minute, Clock(clock), Store(sharded.New()))
+
+	for _, key := range []string{"a", "b", "c", "d"} {
+		g.ThrottleKey(context.Background(), key)
+	}
+
+	//Retries the budget denies must not count e as active every time
+	for i := 0; i < 50; i++ {
+		g.ThrottleKey(context.Background(), "e")
+	}
+
+	current, _ := g.active(context.Background())
+
+	if item, _, _ := g.t.load(current); item.Hits != 4 {
+		t.Fatalf(`Active clients differ.. Expected %d.. Got %d`, 4, item.Hits)
+	}
+}
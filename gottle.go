@@ -6,6 +6,8 @@ import (
 	"encoding/gob"
 	"errors"
 	"net/http"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/adelowo/onecache"
@@ -22,6 +24,10 @@ const (
 var ErrClientIsRateLimited = errors.New(
 	`gottle: The client is currently rate limited`)
 
+//ErrInvalidCost is returned when a request is throttled with a cost below one
+var ErrInvalidCost = errors.New(
+	`gottle: The cost of a request must be at least one`)
+
 //KeyFunc is a function type for setting the key in the cache
 type KeyFunc func(ip string) string
 
@@ -30,6 +36,19 @@ type IPProvider interface {
 	IP(r *http.Request) string
 }
 
+//TimeProvider provides the current time to the throttler.
+//The default implementation uses the system clock, tests can
+//swap in a fake so they don't have to sleep past an interval
+type TimeProvider interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
 //Throttler defines the operation needed to limit clients
 //and check if an HTTP request is currently rate limited
 type Throttler interface {
@@ -37,6 +56,12 @@ type Throttler interface {
 	Clear(r *http.Request) error
 }
 
+//WeightedThrottler is a Throttler whose requests can cost more than one hit
+type WeightedThrottler interface {
+	Throttler
+	ThrottleN(r *http.Request, n int) error
+}
+
 //ThrottlerAttempts provides access to stats about the current request
 type ThrottlerAttempts interface {
 	Attempts(r *http.Request) (int, error)
@@ -50,14 +75,33 @@ type OnecacheThrottler struct {
 	ipProvider   IPProvider
 	store        onecache.Store
 	keyGenerator KeyFunc
+	clock        TimeProvider
+	metrics      MetricsRecorder
+	tracer       Tracer
+	logger       *logger
+	hooks        *hooks
+	hooksOnce    sync.Once
+	overrides    *overrides
+	name         string
 	maxRequests  int
 	interval     time.Duration
+	condition    atomic.Pointer[condition]
+
+	//quota is set for throttlers counting hits in calendar windows
+	quota *quota
+
+	//shadow throttlers record their decisions without enforcing them
+	shadow        bool
+	candidate     *OnecacheThrottler
+	candidateOpts []Option
 }
 
 //NewOneCacheThrottler returns an instance of OnecacheThrottler
 func NewOneCacheThrottler(opts ...Option) *OnecacheThrottler {
 
 	throttler := &OnecacheThrottler{
+		hooks:       new(hooks),
+		name:        defaultName,
 		maxRequests: defaultMaxRequests,
 		interval:    defaultInterval}
 
@@ -82,6 +126,54 @@ func setDefaultsForEmptyFields(throttler *OnecacheThrottler) {
 	if throttler.store == nil {
 		throttler.store = memory.NewInMemoryStore()
 	}
+
+	if throttler.clock == nil {
+		throttler.clock = systemClock{}
+	}
+
+	if _, ok := throttler.store.(WindowedStore); ok && throttler.quota != nil {
+		panic(`gottle: Quotas can't be kept in a store that counts in windows of it's own`)
+	}
+
+	if throttler.candidateOpts != nil {
+		throttler.candidate = newCandidate(throttler, throttler.candidateOpts)
+	}
+
+	throttler.reportLimit()
+}
+
+//now returns the current time according to the configured clock.
+//Throttlers built as struct literals have no clock, so it falls back to the system's
+func (t *OnecacheThrottler) now() time.Time {
+	if t.clock == nil {
+		return time.Now()
+	}
+
+	return t.clock.Now()
+}
+
+//condition is a limit and interval set while the throttler is in use
+type condition struct {
+	maxRequests int
+	interval    time.Duration
+}
+
+//SetThrottleCondition changes the limit and interval of the throttler while
+//it is in use, so they don't need a restart. Requests already being throttled
+//keep the ones they started with. The hits recorded so far are kept
+//and count against the new limit
+func (t *OnecacheThrottler) SetThrottleCondition(interval time.Duration, maxRequests int) {
+	t.condition.Store(&condition{maxRequests: maxRequests, interval: interval})
+	t.reportLimit()
+}
+
+//throttleCondition returns the limit and interval of the throttler
+func (t *OnecacheThrottler) throttleCondition() (int, time.Duration) {
+	if c := t.condition.Load(); c != nil {
+		return c.maxRequests, c.interval
+	}
+
+	return t.maxRequests, t.interval
 }
 
 type throttledItem struct {
@@ -89,15 +181,16 @@ type throttledItem struct {
 	Hits            int
 }
 
-//IsRateLimited checks if a client has reached his/her maximum number of tries
+//IsRateLimited checks if a client has reached his/her maximum number of tries.
+//It is always false in shadow mode
 func (t *OnecacheThrottler) IsRateLimited(r *http.Request) bool {
-	key := t.keyGenerator(t.ipProvider.IP(r))
-
-	if ok := t.store.Has(key); !ok {
+	if t.shadow {
 		return false
 	}
 
-	buf, err := t.store.Get(key)
+	c := t.clientOf(r)
+
+	item, ok, err := t.load(c)
 
 	//--->
 	//Callers of this method expect a bool.
@@ -107,118 +200,98 @@ func (t *OnecacheThrottler) IsRateLimited(r *http.Request) bool {
 	//Not too sure if this is right
 	//but converting the return type to (bool, error) seem weird enough
 
-	if err != nil {
-		return false
-	}
-
-	item := new(throttledItem)
-
-	if err = DecodeGob(buf, item); err != nil {
+	if err != nil || !ok {
 		return false
 	}
 
 	//The user must have made X requests in Y timeframe
-	if item.Hits >= t.maxRequests &&
-		time.Now().Sub(item.LastThrottledAt) <= t.interval {
-		return true
-	}
-
-	return false
+	return t.limited(c, item)
 }
 
-//Throttle throttles an HTTP request
+//Throttle throttles an HTTP request.
+//In shadow mode, the request is recorded but never rejected
 func (t *OnecacheThrottler) Throttle(r *http.Request) error {
 
-	if t.IsRateLimited(r) {
-		return ErrClientIsRateLimited
-	}
-
-	key := t.keyGenerator(t.ipProvider.IP(r))
-
-	if ok := t.store.Has(key); ok {
-
-		buf, err := t.store.Get(key)
-
-		if err != nil {
-			return err
-		}
-
-		item := new(throttledItem)
-
-		if err := DecodeGob(buf, item); err != nil {
-			return err
-		}
-
-		item.LastThrottledAt = time.Now()
-		item.Hits += defaultThrottledItemIncrement
-
-		buf, err = EncodeGob(item)
+	return t.ThrottleN(r, defaultThrottledItemIncrement)
+}
 
-		if err != nil {
-			return err
-		}
+//ThrottleN throttles an HTTP request that costs n hits, for requests
+//far more expensive than others. It is rate limited if the n hits would
+//take the client past it's limit, in which case none of them are recorded,
+//so a request costing more than the limit is never let through
+func (t *OnecacheThrottler) ThrottleN(r *http.Request, n int) error {
+	if n < 1 {
+		return ErrInvalidCost
+	}
 
-		if err := t.store.Set(key, buf, t.interval); err != nil {
-			return err
-		}
+	err := t.decide(t.clientOf(r), n)
 
-		return nil
+	if t.candidate != nil {
+		t.candidate.decide(t.candidate.clientOf(r), n)
 	}
 
-	item := &throttledItem{
-		Hits: 1, LastThrottledAt: time.Now()}
+	return err
+}
 
-	byt, err := EncodeGob(item)
+//decide records n hits for c and reports the outcome
+func (t *OnecacheThrottler) decide(c client, n int) error {
+	hits, err := t.throttle(c, n)
 
-	if err != nil {
-		return err
-	}
+	t.decided(c, hits, n, err)
 
-	if err = t.store.Set(key, byt, t.interval); err != nil {
-		return err
+	//Store errors have already been reported,
+	//a shadow throttler must not fail the request either
+	if t.shadow {
+		return nil
 	}
 
-	return nil
+	return err
 }
 
 //Clear resets the throttle on the request
 func (t *OnecacheThrottler) Clear(r *http.Request) error {
 
-	key := t.keyGenerator(t.ipProvider.IP(r))
+	return t.clear(t.clientOf(r))
+}
+
+func (t *OnecacheThrottler) clear(c client) error {
 
 	//It should be a no-op for requests that have not been throttled before
-	if !t.store.Has(key) {
+	if !t.has(c) {
 		return nil
 	}
 
-	if err := t.store.Delete(key); err != nil {
+	err := t.timed(c, OpDelete, func() error {
+		return t.store.Delete(c.entry())
+	})
+
+	if err != nil {
 		return err
 	}
 
+	t.registry().cleared(t.event(c, 0))
+
 	return nil
 }
 
 //Attempts returns the number of times the request have been throttled
 func (t *OnecacheThrottler) Attempts(r *http.Request) (int, error) {
 
-	key := t.keyGenerator(t.ipProvider.IP(r))
+	return t.attempts(t.clientOf(r))
+}
 
-	if !t.store.Has(key) {
-		return -1, errors.New(`
-			gottle: Cannot get the number of attempts left as the current
-			request has not been throttled or it has previously been cleared out`)
-	}
+func (t *OnecacheThrottler) attempts(c client) (int, error) {
 
-	buf, err := t.store.Get(key)
+	item, ok, err := t.load(c)
 
 	if err != nil {
 		return -1, err
 	}
 
-	item := new(throttledItem)
-
-	if err := DecodeGob(buf, item); err != nil {
-		return -1, err
+	if !ok {
+		return -1, errors.New(`
+			gottle: Cannot get the number of attempts left as the current
+			request has not been throttled or it has previously been cleared out`)
 	}
 
 	return item.Hits, nil
@@ -226,13 +299,15 @@ func (t *OnecacheThrottler) Attempts(r *http.Request) (int, error) {
 
 //AttemptsLeft gets the number of attempts left before a lockout is obtained
 func (t *OnecacheThrottler) AttemptsLeft(r *http.Request) (int, error) {
-	attempts, err := t.Attempts(r)
+	c := t.clientOf(r)
+
+	attempts, err := t.attempts(c)
 
 	if err != nil {
 		return -1, err
 	}
 
-	return (t.maxRequests - attempts), nil
+	return (c.limit - attempts), nil
 }
 
 //Default implementation of KeyFunc
@@ -4,10 +4,13 @@ import (
 	"testing"
 	"time"
 
+	"github.com/adelowo/gottle/sharded"
+	"github.com/adelowo/onecache"
 	"github.com/adelowo/onecache/memory"
 )
 
 var _ Throttler = NewOneCacheThrottler()
+var _ WeightedThrottler = NewOneCacheThrottler()
 
 func TestOnecacheThrottler_Throttle(t *testing.T) {
 
@@ -101,6 +104,58 @@ func TestOnecacheThrottler_Throttle_multipleTimes(t *testing.T) {
 	}
 }
 
+func TestOnecacheThrottler_ThrottleN(t *testing.T) {
+	stores := map[string]func() onecache.Store{
+		"gob":           func() onecache.Store { return memory.New() },
+		"limit counter": func() onecache.Store { return sharded.New() },
+	}
+
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			r, teardown, err := setUp(t)
+			defer teardown()
+
+			if err != nil {
+				t.Fatalf("An error occurred while setting up the test ..%v", err)
+			}
+
+			r.Header.Set(xForwardedFor, "123.456.789.000")
+
+			throttler := NewOneCacheThrottler(
+				Store(store()), ThrottleCondition(time.Minute, 10))
+
+			if err := throttler.ThrottleN(r, 0); err != ErrInvalidCost {
+				t.Fatalf(`Errors differ.. Expected %v.. Got %v`, ErrInvalidCost, err)
+			}
+
+			if err := throttler.ThrottleN(r, 11); err != ErrClientIsRateLimited {
+				t.Fatalf(`Expected a cost over the limit to be denied.. Got %v`, err)
+			}
+
+			if err := throttler.ThrottleN(r, 7); err != nil {
+				t.Fatalf(`An error occurred while throttling the request .. %v`, err)
+			}
+
+			//A request costing more than what is left is denied without being recorded
+			if err := throttler.ThrottleN(r, 4); err != ErrClientIsRateLimited {
+				t.Fatalf(`Errors differ.. Expected %v.. Got %v`, ErrClientIsRateLimited, err)
+			}
+
+			if err := throttler.ThrottleN(r, 3); err != nil {
+				t.Fatalf(`Expected the last 3 hits to be allowed.. Got %v`, err)
+			}
+
+			if attempts, _ := throttler.Attempts(r); attempts != 10 {
+				t.Fatalf(`Attempts differ.. Expected %d.. Got %d`, 10, attempts)
+			}
+
+			if !throttler.IsRateLimited(r) {
+				t.Fatal(`Expected the client to be rate limited`)
+			}
+		})
+	}
+}
+
 func TestOnecacheThrottler_IsRateLimited(t *testing.T) {
 	r, teardown, err := setUp(t)
 	defer teardown()
@@ -345,3 +400,42 @@ func TestOnecacheThrottler_AttemptsLeft_errorsOut(t *testing.T) {
 			Expected %d attempts. Got %d`, expectedNumberOfAttemptsLeft, attemptsLeftTillLockout)
 	}
 }
+
+func TestOnecacheThrottler_SetThrottleCondition(t *testing.T) {
+	r, teardown, err := setUp(t)
+	defer teardown()
+
+	if err != nil {
+		t.Fatalf("An error occurred while setting up the test ..%v", err)
+	}
+
+	r.Header.Set(xForwardedFor, "123.456.789.000")
+
+	throttler := NewOneCacheThrottler(ThrottleCondition(time.Minute, 2))
+
+	for i := 0; i < 2; i++ {
+		throttler.Throttle(r)
+	}
+
+	if !throttler.IsRateLimited(r) {
+		t.Fatal(`Expected the client to be rate limited`)
+	}
+
+	throttler.SetThrottleCondition(time.Hour, 3)
+
+	//The hits recorded so far count against the new limit
+	if throttler.IsRateLimited(r) {
+		t.Fatal(`The client is not supposed to be limited under the new limit`)
+	}
+
+	if err := throttler.Throttle(r); err != nil {
+		t.Fatalf(`An error occurred while throttling the request .. %v`, err)
+	}
+
+	status, _ := throttler.Status("123.456.789.000")
+
+	if status.Hits != 3 || status.Limit != 3 || !status.Limited ||
+		status.ResetAt.Sub(time.Now()) < time.Minute*59 {
+		t.Fatalf(`The new condition was not applied.. Got %+v`, status)
+	}
+}
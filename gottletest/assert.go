@@ -0,0 +1,53 @@
+package gottletest
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/adelowo/gottle"
+)
+
+//Limiter is the part of a throttler the assertions need.
+//Both gottle.OnecacheThrottler and the fake Throttler satisfy it
+type Limiter interface {
+	gottle.Throttler
+	IsRateLimited(r *http.Request) bool
+}
+
+//AssertLimitedAfter throttles r n times, failing the test if the client
+//gets rate limited before that or isn't rate limited afterwards
+func AssertLimitedAfter(t testing.TB, throttler Limiter, r *http.Request, n int) {
+	t.Helper()
+
+	for i := 0; i < n; i++ {
+		if throttler.IsRateLimited(r) {
+			t.Errorf(`gottletest: The client was rate limited after %d requests..
+				Expected it to be limited after %d`, i, n)
+			return
+		}
+
+		if err := throttler.Throttle(r); err != nil {
+			t.Errorf(`gottletest: Throttling request %d of %d failed.. %v`, i+1, n, err)
+			return
+		}
+	}
+
+	if !throttler.IsRateLimited(r) {
+		t.Errorf(`gottletest: The client was not rate limited after %d requests`, n)
+		return
+	}
+
+	if err := throttler.Throttle(r); err != gottle.ErrClientIsRateLimited {
+		t.Errorf(`gottletest: Throttling a rate limited client..
+			Expected %v.. Got %v`, gottle.ErrClientIsRateLimited, err)
+	}
+}
+
+//AssertNotLimited fails the test if the client is rate limited
+func AssertNotLimited(t testing.TB, throttler Limiter, r *http.Request) {
+	t.Helper()
+
+	if throttler.IsRateLimited(r) {
+		t.Errorf(`gottletest: The client is not supposed to be rate limited`)
+	}
+}
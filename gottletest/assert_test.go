@@ -0,0 +1,53 @@
+package gottletest
+
+import (
+	"fmt"
+	"testing"
+)
+
+//recorder is a testing.TB that records failures instead of failing the test
+type recorder struct {
+	testing.TB
+	failures []string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...interface{}) {
+	r.failures = append(r.failures, fmt.Sprintf(format, args...))
+}
+
+func TestAssertLimitedAfter_fails(t *testing.T) {
+	cases := []struct {
+		limit, n int
+	}{
+		{5, 2}, //never gets limited
+		{2, 5}, //gets limited too early
+	}
+
+	for _, v := range cases {
+		rec := &recorder{TB: t}
+
+		AssertLimitedAfter(rec, NewThrottler(v.limit), newRequest("123.456.789.000"), v.n)
+
+		if len(rec.failures) != 1 {
+			t.Fatalf(`Expected the assertion to fail once for a limit of %d after %d requests..
+				Got %v`, v.limit, v.n, rec.failures)
+		}
+	}
+}
+
+func TestAssertNotLimited_fails(t *testing.T) {
+	rec := &recorder{TB: t}
+
+	throttler := NewThrottler(1)
+	r := newRequest("123.456.789.000")
+
+	throttler.Throttle(r)
+
+	AssertNotLimited(rec, throttler, r)
+
+	if len(rec.failures) != 1 {
+		t.Fatalf(`Expected the assertion to fail.. Got %v`, rec.failures)
+	}
+}
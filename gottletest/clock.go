@@ -0,0 +1,58 @@
+package gottletest
+
+import (
+	"sync"
+	"time"
+)
+
+//Clock is a fake gottle.TimeProvider whose time only moves when told to.
+//It can also be scripted with a sequence of readings to return
+type Clock struct {
+	mu       sync.Mutex
+	current  time.Time
+	readings []time.Time
+}
+
+//NewClock returns a Clock stopped at start
+func NewClock(start time.Time) *Clock {
+	return &Clock{current: start}
+}
+
+//Now returns the next scripted reading if there is one,
+//else the time the clock is currently stopped at
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.readings) > 0 {
+		c.current = c.readings[0]
+		c.readings = c.readings[1:]
+	}
+
+	return c.current
+}
+
+//Advance moves the clock forward by d
+func (c *Clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.current = c.current.Add(d)
+}
+
+//Set stops the clock at t
+func (c *Clock) Set(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.current = t
+}
+
+//Script queues readings to be returned, one per call to Now.
+//Once they have all been read, the clock stays at the last one
+func (c *Clock) Script(readings ...time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.readings = append(c.readings, readings...)
+}
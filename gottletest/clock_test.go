@@ -0,0 +1,68 @@
+package gottletest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/adelowo/gottle"
+)
+
+var _ gottle.TimeProvider = &Clock{}
+
+func TestClock_Advance(t *testing.T) {
+	start := time.Date(2017, time.March, 1, 0, 0, 0, 0, time.UTC)
+
+	clock := NewClock(start)
+
+	if now := clock.Now(); !now.Equal(start) {
+		t.Fatalf(`Time differs.. Expected %v.. Got %v`, start, now)
+	}
+
+	clock.Advance(time.Minute)
+
+	if now := clock.Now(); !now.Equal(start.Add(time.Minute)) {
+		t.Fatalf(`Time differs.. Expected %v.. Got %v`, start.Add(time.Minute), now)
+	}
+}
+
+func TestClock_Script(t *testing.T) {
+	start := time.Date(2017, time.March, 1, 0, 0, 0, 0, time.UTC)
+
+	clock := NewClock(start)
+
+	readings := []time.Time{
+		start.Add(time.Second),
+		start.Add(time.Hour),
+	}
+
+	clock.Script(readings...)
+
+	for _, expected := range readings {
+		if now := clock.Now(); !now.Equal(expected) {
+			t.Fatalf(`Time differs.. Expected %v.. Got %v`, expected, now)
+		}
+	}
+
+	//The clock should stay at the last reading
+	if now := clock.Now(); !now.Equal(readings[1]) {
+		t.Fatalf(`Time differs.. Expected %v.. Got %v`, readings[1], now)
+	}
+}
+
+func TestClock_withThrottler(t *testing.T) {
+	r := newRequest("123.456.789.000")
+
+	clock := NewClock(time.Now())
+
+	throttler := gottle.NewOneCacheThrottler(
+		gottle.Clock(clock),
+		gottle.ThrottleCondition(time.Minute, 2))
+
+	AssertLimitedAfter(t, throttler, r, 2)
+
+	//Moving past the interval should free the client
+	//without the test having to sleep
+	clock.Advance(time.Minute + time.Second)
+
+	AssertNotLimited(t, throttler, r)
+}
@@ -0,0 +1,161 @@
+package gottletest
+
+import (
+	"sync"
+	"time"
+
+	"github.com/adelowo/gottle"
+	"github.com/adelowo/onecache"
+	"github.com/adelowo/onecache/memory"
+)
+
+//Op identifies an operation on a onecache.Store
+type Op string
+
+//Operations that can be counted or made to fail on a Store
+const (
+	OpSet    Op = "set"
+	OpGet    Op = "get"
+	OpDelete Op = "delete"
+	OpFlush  Op = "flush"
+	OpHas    Op = "has"
+)
+
+//Store is an instrumented onecache.Store.
+//It counts every operation, can make them fail with a given error
+//and can add latency to them, before handing off to a real store
+type Store struct {
+	inner onecache.Store
+
+	mu      sync.Mutex
+	calls   map[Op]int
+	errs    map[Op]error
+	latency time.Duration
+}
+
+//NewStore returns a Store that wraps inner.
+//If inner is nil, an in memory store is used
+func NewStore(inner onecache.Store) *Store {
+	if inner == nil {
+		inner = memory.New()
+	}
+
+	return &Store{
+		inner: inner,
+		calls: make(map[Op]int),
+		errs:  make(map[Op]error),
+	}
+}
+
+//Fail makes every subsequent call to op return err.
+//Has has no error to return, so a failing Has reports false.
+//A nil err stops op from failing
+func (s *Store) Fail(op Op, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err == nil {
+		delete(s.errs, op)
+		return
+	}
+
+	s.errs[op] = err
+}
+
+//Delay makes every operation sleep for d before it is carried out
+func (s *Store) Delay(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.latency = d
+}
+
+//Calls returns the number of times op has been called
+func (s *Store) Calls(op Op) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.calls[op]
+}
+
+//Reset clears the call counts, injected errors and latency.
+//The data in the wrapped store is left as is
+func (s *Store) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.calls = make(map[Op]int)
+	s.errs = make(map[Op]error)
+	s.latency = 0
+}
+
+func (s *Store) record(op Op) error {
+	s.mu.Lock()
+	s.calls[op]++
+	err := s.errs[op]
+	latency := s.latency
+	s.mu.Unlock()
+
+	if latency > 0 {
+		time.Sleep(latency)
+	}
+
+	return err
+}
+
+//Set stores data in the wrapped store
+func (s *Store) Set(key string, data []byte, expires time.Duration) error {
+	if err := s.record(OpSet); err != nil {
+		return err
+	}
+
+	return s.inner.Set(key, data, expires)
+}
+
+//Get fetches data from the wrapped store
+func (s *Store) Get(key string) ([]byte, error) {
+	if err := s.record(OpGet); err != nil {
+		return nil, err
+	}
+
+	return s.inner.Get(key)
+}
+
+//Delete removes key from the wrapped store
+func (s *Store) Delete(key string) error {
+	if err := s.record(OpDelete); err != nil {
+		return err
+	}
+
+	return s.inner.Delete(key)
+}
+
+//Flush clears the wrapped store
+func (s *Store) Flush() error {
+	if err := s.record(OpFlush); err != nil {
+		return err
+	}
+
+	return s.inner.Flush()
+}
+
+//Enumerate lists the keys of the wrapped store.
+//gottle.ErrEnumerationNotSupported is returned if it can't list it's keys
+func (s *Store) Enumerate(prefix string, fn func(key string) bool) error {
+	enumerator, ok := s.inner.(gottle.Enumerator)
+
+	if !ok {
+		return gottle.ErrEnumerationNotSupported
+	}
+
+	return enumerator.Enumerate(prefix, fn)
+}
+
+//Has checks if key exists in the wrapped store
+func (s *Store) Has(key string) bool {
+	if err := s.record(OpHas); err != nil {
+		return false
+	}
+
+	return s.inner.Has(key)
+}
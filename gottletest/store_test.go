@@ -0,0 +1,125 @@
+package gottletest
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/adelowo/gottle"
+	"github.com/adelowo/onecache"
+	"github.com/adelowo/onecache/memory"
+)
+
+var _ onecache.Store = &Store{}
+var _ gottle.Enumerator = &Store{}
+
+//enumerating is an in memory store that can list a fixed set of keys
+type enumerating struct {
+	onecache.Store
+	keys []string
+}
+
+func (e enumerating) Enumerate(prefix string, fn func(key string) bool) error {
+	for _, key := range e.keys {
+		if strings.HasPrefix(key, prefix) && !fn(key) {
+			break
+		}
+	}
+
+	return nil
+}
+
+func TestStore_Calls(t *testing.T) {
+	store := NewStore(nil)
+
+	if err := store.Set("key", []byte("value"), time.Minute); err != nil {
+		t.Fatalf(`An error occurred while setting the key.. %v`, err)
+	}
+
+	if !store.Has("key") {
+		t.Fatal(`Expected the key to exist in the wrapped store`)
+	}
+
+	if _, err := store.Get("key"); err != nil {
+		t.Fatalf(`An error occurred while fetching the key.. %v`, err)
+	}
+
+	cases := []struct {
+		op       Op
+		expected int
+	}{
+		{OpSet, 1},
+		{OpHas, 1},
+		{OpGet, 1},
+		{OpDelete, 0},
+	}
+
+	for _, v := range cases {
+		if actual := store.Calls(v.op); actual != v.expected {
+			t.Fatalf(`Calls to %s differ.. Expected %d.. Got %d`,
+				v.op, v.expected, actual)
+		}
+	}
+}
+
+func TestStore_Fail(t *testing.T) {
+	store := NewStore(nil)
+
+	expectedErr := errors.New("oops")
+
+	store.Fail(OpSet, expectedErr)
+
+	r := newRequest("123.456.789.000")
+
+	throttler := gottle.NewOneCacheThrottler(gottle.Store(store))
+
+	if err := throttler.Throttle(r); err != expectedErr {
+		t.Fatalf(`Errors differ.. Expected %v.. Got %v`, expectedErr, err)
+	}
+
+	store.Fail(OpSet, nil)
+
+	if err := throttler.Throttle(r); err != nil {
+		t.Fatalf(`An error occurred after the failure was removed.. %v`, err)
+	}
+}
+
+func TestStore_Delay(t *testing.T) {
+	store := NewStore(nil)
+
+	store.Delay(time.Millisecond * 20)
+
+	start := time.Now()
+
+	store.Has("key")
+
+	if elapsed := time.Since(start); elapsed < time.Millisecond*20 {
+		t.Fatalf(`Expected the operation to take at least %v.. It took %v`,
+			time.Millisecond*20, elapsed)
+	}
+}
+
+func TestStore_Enumerate(t *testing.T) {
+	store := NewStore(nil)
+
+	noop := func(string) bool { return true }
+
+	if err := store.Enumerate("", noop); err != gottle.ErrEnumerationNotSupported {
+		t.Fatalf(`Errors differ.. Expected %v.. Got %v`, gottle.ErrEnumerationNotSupported, err)
+	}
+
+	store = NewStore(enumerating{memory.New(), []string{"login-1", "api-1", "login-2"}})
+
+	var keys []string
+
+	err := store.Enumerate("login-", func(key string) bool {
+		keys = append(keys, key)
+		return true
+	})
+
+	if err != nil || len(keys) != 2 {
+		t.Fatalf(`Keys differ.. Expected %v.. Got %v, %v`,
+			[]string{"login-1", "login-2"}, keys, err)
+	}
+}
@@ -0,0 +1,169 @@
+//Package gottletest provides fakes and assertions for testing code
+//that makes use of gottle without reaching into it's internals
+package gottletest
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+
+	"github.com/adelowo/gottle"
+)
+
+//Methods of the Throttler that get recorded as a Call
+const (
+	MethodThrottle      = "Throttle"
+	MethodThrottleN     = "ThrottleN"
+	MethodClear         = "Clear"
+	MethodIsRateLimited = "IsRateLimited"
+	MethodAttempts      = "Attempts"
+	MethodAttemptsLeft  = "AttemptsLeft"
+)
+
+//Call is a single recorded call made on a Throttler
+type Call struct {
+	Method  string
+	Request *http.Request
+}
+
+//Throttler is a fake gottle.Throttler that records every call made on it.
+//It keeps a single hit count for all requests,
+//which is reset by Clear
+type Throttler struct {
+	//Limit is the number of hits after which the fake reports the client
+	//as rate limited. A zero value never limits
+	Limit int
+
+	//ThrottleErr, when set, is returned by Throttle without recording a hit
+	ThrottleErr error
+
+	//ClearErr, when set, is returned by Clear without resetting the hits
+	ClearErr error
+
+	mu    sync.Mutex
+	hits  int
+	calls []Call
+}
+
+//NewThrottler returns a fake Throttler that limits after limit hits
+func NewThrottler(limit int) *Throttler {
+	return &Throttler{Limit: limit}
+}
+
+func (f *Throttler) record(method string, r *http.Request) {
+	f.calls = append(f.calls, Call{Method: method, Request: r})
+}
+
+func (f *Throttler) limited() bool {
+	return f.Limit > 0 && f.hits >= f.Limit
+}
+
+//Throttle records a hit, or returns gottle.ErrClientIsRateLimited
+//once Limit has been reached
+func (f *Throttler) Throttle(r *http.Request) error {
+	return f.throttle(MethodThrottle, r, 1)
+}
+
+//ThrottleN records n hits, or returns gottle.ErrClientIsRateLimited
+//if they would go past Limit
+func (f *Throttler) ThrottleN(r *http.Request, n int) error {
+	return f.throttle(MethodThrottleN, r, n)
+}
+
+func (f *Throttler) throttle(method string, r *http.Request, n int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.record(method, r)
+
+	if f.ThrottleErr != nil {
+		return f.ThrottleErr
+	}
+
+	if f.Limit > 0 && f.hits+n > f.Limit {
+		return gottle.ErrClientIsRateLimited
+	}
+
+	f.hits += n
+	return nil
+}
+
+//Clear resets the hits
+func (f *Throttler) Clear(r *http.Request) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.record(MethodClear, r)
+
+	if f.ClearErr != nil {
+		return f.ClearErr
+	}
+
+	f.hits = 0
+	return nil
+}
+
+//IsRateLimited reports if Limit has been reached
+func (f *Throttler) IsRateLimited(r *http.Request) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.record(MethodIsRateLimited, r)
+
+	return f.limited()
+}
+
+//Attempts returns the number of hits recorded so far
+func (f *Throttler) Attempts(r *http.Request) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.record(MethodAttempts, r)
+
+	if f.hits == 0 {
+		return -1, errors.New("gottletest: The request has not been throttled")
+	}
+
+	return f.hits, nil
+}
+
+//AttemptsLeft returns the number of hits left before Limit is reached
+func (f *Throttler) AttemptsLeft(r *http.Request) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.record(MethodAttemptsLeft, r)
+
+	if f.hits == 0 {
+		return -1, errors.New("gottletest: The request has not been throttled")
+	}
+
+	return f.Limit - f.hits, nil
+}
+
+//Calls returns every call made on the fake, in order
+func (f *Throttler) Calls() []Call {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	calls := make([]Call, len(f.calls))
+	copy(calls, f.calls)
+
+	return calls
+}
+
+//Count returns the number of calls made to method
+func (f *Throttler) Count(method string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var n int
+
+	for _, c := range f.calls {
+		if c.Method == method {
+			n++
+		}
+	}
+
+	return n
+}
@@ -0,0 +1,76 @@
+package gottletest
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/adelowo/gottle"
+)
+
+var _ gottle.Throttler = &Throttler{}
+var _ gottle.WeightedThrottler = &Throttler{}
+var _ gottle.ThrottlerAttempts = &Throttler{}
+
+func newRequest(ip string) *http.Request {
+	r := httptest.NewRequest(http.MethodGet, "/oops", nil)
+	r.Header.Set("X-Forwarded-For", ip)
+	return r
+}
+
+func TestThrottler(t *testing.T) {
+	r := newRequest("123.456.789.000")
+
+	throttler := NewThrottler(3)
+
+	AssertLimitedAfter(t, throttler, r, 3)
+
+	if err := throttler.Clear(r); err != nil {
+		t.Fatalf(`An error occurred while clearing the fake.. %v`, err)
+	}
+
+	AssertNotLimited(t, throttler, r)
+
+	if n := throttler.Count(MethodThrottle); n != 4 {
+		t.Fatalf(`Calls to Throttle differ.. Expected %d.. Got %d`, 4, n)
+	}
+
+	calls := throttler.Calls()
+
+	if calls[0].Method != MethodIsRateLimited || calls[0].Request != r {
+		t.Fatalf(`The first call was not recorded properly.. Got %v`, calls[0])
+	}
+}
+
+func TestThrottler_ThrottleN(t *testing.T) {
+	r := newRequest("123.456.789.000")
+
+	throttler := NewThrottler(5)
+
+	if err := throttler.ThrottleN(r, 4); err != nil {
+		t.Fatalf(`An error occurred while throttling the fake.. %v`, err)
+	}
+
+	if err := throttler.ThrottleN(r, 2); err != gottle.ErrClientIsRateLimited {
+		t.Fatalf(`Errors differ.. Expected %v.. Got %v`, gottle.ErrClientIsRateLimited, err)
+	}
+
+	if err := throttler.Throttle(r); err != nil {
+		t.Fatalf(`Expected the last hit to be allowed.. Got %v`, err)
+	}
+
+	if n := throttler.Count(MethodThrottleN); n != 2 {
+		t.Fatalf(`Calls to ThrottleN differ.. Expected %d.. Got %d`, 2, n)
+	}
+}
+
+func TestThrottler_ThrottleErr(t *testing.T) {
+	expectedErr := errors.New("oops")
+
+	throttler := &Throttler{Limit: 1, ThrottleErr: expectedErr}
+
+	if err := throttler.Throttle(newRequest("123.456.789.000")); err != expectedErr {
+		t.Fatalf(`Errors differ.. Expected %v.. Got %v`, expectedErr, err)
+	}
+}
@@ -0,0 +1,124 @@
+//Package grpc throttles gRPC servers with unary and stream interceptors.
+//
+//Calls are identified by a KeyFunc, the peer's IP by default, and throttled
+//through a gottle throttler. Rate limited calls fail with
+//codes.ResourceExhausted and a RetryInfo detail telling the client when to
+//try again. Calls that can't be throttled because of a store error are let through
+package grpc
+
+import (
+	"context"
+	"time"
+
+	"github.com/adelowo/gottle"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	gogrpc "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+//Throttler is what the interceptors need from a throttler.
+//gottle.OnecacheThrottler implements it
+type Throttler interface {
+	ThrottleKey(ctx context.Context, key string) error
+	Status(key string) (gottle.KeyStatus, error)
+}
+
+//Interceptor throttles the calls made to a gRPC server
+type Interceptor struct {
+	throttler  Throttler
+	key        KeyFunc
+	perMessage bool
+}
+
+//New returns an Interceptor that throttles calls through throttler
+func New(throttler Throttler, opts ...Option) *Interceptor {
+	i := &Interceptor{
+		throttler: throttler,
+		key:       PeerIP(),
+	}
+
+	for _, opt := range opts {
+		opt(i)
+	}
+
+	return i
+}
+
+//throttle throttles the call to method, returning the status
+//error to fail it with if it is rate limited
+func (i *Interceptor) throttle(ctx context.Context, method string) error {
+	key := i.key(ctx, method)
+
+	if err := i.throttler.ThrottleKey(ctx, key); err != gottle.ErrClientIsRateLimited {
+		return nil
+	}
+
+	st := status.New(codes.ResourceExhausted, gottle.ErrClientIsRateLimited.Error())
+
+	keyStatus, err := i.throttler.Status(key)
+
+	if err != nil || keyStatus.ResetAt.IsZero() {
+		return st.Err()
+	}
+
+	delay := time.Until(keyStatus.ResetAt)
+
+	if delay < 0 {
+		delay = 0
+	}
+
+	if detailed, err := st.WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(delay)}); err == nil {
+		st = detailed
+	}
+
+	return st.Err()
+}
+
+//Unary returns a unary server interceptor
+func (i *Interceptor) Unary() gogrpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *gogrpc.UnaryServerInfo,
+		handler gogrpc.UnaryHandler) (interface{}, error) {
+
+		if err := i.throttle(ctx, info.FullMethod); err != nil {
+			return nil, err
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+//Stream returns a stream server interceptor.
+//The stream is throttled when it is opened and, with the PerMessage option,
+//every message received on it is throttled as well
+func (i *Interceptor) Stream() gogrpc.StreamServerInterceptor {
+	return func(srv interface{}, ss gogrpc.ServerStream, info *gogrpc.StreamServerInfo,
+		handler gogrpc.StreamHandler) error {
+
+		if err := i.throttle(ss.Context(), info.FullMethod); err != nil {
+			return err
+		}
+
+		if i.perMessage {
+			ss = &stream{ServerStream: ss, interceptor: i, method: info.FullMethod}
+		}
+
+		return handler(srv, ss)
+	}
+}
+
+//stream throttles every message received on a ServerStream
+type stream struct {
+	gogrpc.ServerStream
+	interceptor *Interceptor
+	method      string
+}
+
+func (s *stream) RecvMsg(m interface{}) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+
+	return s.interceptor.throttle(s.Context(), s.method)
+}
@@ -0,0 +1,200 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/adelowo/gottle"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	gogrpc "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+var _ Throttler = &gottle.OnecacheThrottler{}
+
+//echo sends back every message it receives on a stream
+func echo(srv interface{}, ss gogrpc.ServerStream) error {
+	for {
+		msg := new(emptypb.Empty)
+
+		if err := ss.RecvMsg(msg); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+
+			return err
+		}
+
+		if err := ss.SendMsg(msg); err != nil {
+			return err
+		}
+	}
+}
+
+//serve starts a server with the interceptor over an in memory connection
+//and returns a client connection to it
+func serve(t *testing.T, i *Interceptor) *gogrpc.ClientConn {
+	t.Helper()
+
+	lis := bufconn.Listen(1024 * 1024)
+
+	srv := gogrpc.NewServer(
+		gogrpc.UnaryInterceptor(i.Unary()),
+		gogrpc.StreamInterceptor(i.Stream()),
+		gogrpc.UnknownServiceHandler(echo))
+
+	healthpb.RegisterHealthServer(srv, health.NewServer())
+
+	go srv.Serve(lis)
+
+	conn, err := gogrpc.NewClient("passthrough:///bufnet",
+		gogrpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		gogrpc.WithTransportCredentials(insecure.NewCredentials()))
+
+	if err != nil {
+		t.Fatalf(`Could not connect to the server.. %v`, err)
+	}
+
+	t.Cleanup(func() {
+		conn.Close()
+		srv.Stop()
+	})
+
+	return conn
+}
+
+func assertRateLimited(t *testing.T, err error) {
+	t.Helper()
+
+	st, ok := status.FromError(err)
+
+	if !ok || st.Code() != codes.ResourceExhausted {
+		t.Fatalf(`Expected the call to fail with %v.. Got %v`, codes.ResourceExhausted, err)
+	}
+
+	for _, detail := range st.Details() {
+		if info, ok := detail.(*errdetails.RetryInfo); ok {
+			if delay := info.RetryDelay.AsDuration(); delay <= 0 || delay > time.Minute {
+				t.Fatalf(`Expected a retry delay of at most a minute.. Got %v`, delay)
+			}
+
+			return
+		}
+	}
+
+	t.Fatalf(`Expected the status to carry retry info.. Got %v`, st.Details())
+}
+
+func TestInterceptor_Unary(t *testing.T) {
+	throttler := gottle.NewOneCacheThrottler(
+		gottle.ThrottleCondition(time.Minute, 2))
+
+	client := healthpb.NewHealthClient(serve(t, New(throttler)))
+
+	for i := 0; i < 2; i++ {
+		if _, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{}); err != nil {
+			t.Fatalf(`Expected call %d to be allowed.. Got %v`, i+1, err)
+		}
+	}
+
+	_, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{})
+
+	assertRateLimited(t, err)
+}
+
+func TestInterceptor_Stream(t *testing.T) {
+	desc := &gogrpc.StreamDesc{ClientStreams: true, ServerStreams: true}
+
+	t.Run("opening", func(t *testing.T) {
+		throttler := gottle.NewOneCacheThrottler(
+			gottle.ThrottleCondition(time.Minute, 1))
+
+		conn := serve(t, New(throttler, Key(Method())))
+
+		for i := 0; i < 5; i++ {
+			s, err := conn.NewStream(context.Background(), desc, "/test.Echo/Chat")
+
+			if err != nil {
+				t.Fatalf(`Could not open the stream.. %v`, err)
+			}
+
+			if err := s.SendMsg(&emptypb.Empty{}); err != nil {
+				t.Fatalf(`Could not send a message.. %v`, err)
+			}
+
+			err = s.RecvMsg(new(emptypb.Empty))
+
+			if i == 0 {
+				if err != nil {
+					t.Fatalf(`Expected the first stream to be allowed.. Got %v`, err)
+				}
+
+				s.CloseSend()
+				continue
+			}
+
+			assertRateLimited(t, err)
+		}
+
+		if status, _ := throttler.Status("/test.Echo/Chat"); status.Hits != 1 {
+			t.Fatalf(`Expected only the stream to be counted.. Got %d hits`, status.Hits)
+		}
+	})
+
+	t.Run("per message", func(t *testing.T) {
+		throttler := gottle.NewOneCacheThrottler(
+			gottle.ThrottleCondition(time.Minute, 3))
+
+		conn := serve(t, New(throttler, PerMessage(true)))
+
+		s, err := conn.NewStream(context.Background(), desc, "/test.Echo/Chat")
+
+		if err != nil {
+			t.Fatalf(`Could not open the stream.. %v`, err)
+		}
+
+		//Opening the stream uses up the first attempt
+		for i := 0; i < 2; i++ {
+			s.SendMsg(&emptypb.Empty{})
+
+			if err := s.RecvMsg(new(emptypb.Empty)); err != nil {
+				t.Fatalf(`Expected message %d to be allowed.. Got %v`, i+1, err)
+			}
+		}
+
+		s.SendMsg(&emptypb.Empty{})
+
+		assertRateLimited(t, s.RecvMsg(new(emptypb.Empty)))
+	})
+}
+
+//failingThrottler can't reach it's store
+type failingThrottler struct{}
+
+func (failingThrottler) ThrottleKey(ctx context.Context, key string) error {
+	return errors.New("store is down")
+}
+
+func (failingThrottler) Status(key string) (gottle.KeyStatus, error) {
+	return gottle.KeyStatus{}, errors.New("store is down")
+}
+
+func TestInterceptor_storeErrors(t *testing.T) {
+	client := healthpb.NewHealthClient(serve(t, New(failingThrottler{})))
+
+	if _, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{}); err != nil {
+		t.Fatalf(`Expected the call to be let through.. Got %v`, err)
+	}
+}
@@ -0,0 +1,75 @@
+package grpc
+
+import (
+	"context"
+	"net"
+	"strings"
+
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+//KeyFunc returns the key a call to method is throttled under
+type KeyFunc func(ctx context.Context, method string) string
+
+//PeerIP is a KeyFunc that identifies calls by the IP of the peer making them
+func PeerIP() KeyFunc {
+	return func(ctx context.Context, method string) string {
+		p, ok := peer.FromContext(ctx)
+
+		if !ok || p.Addr == nil {
+			return ""
+		}
+
+		addr := p.Addr.String()
+
+		host, _, err := net.SplitHostPort(addr)
+
+		if err != nil {
+			return addr
+		}
+
+		return host
+	}
+}
+
+//Metadata is a KeyFunc that identifies calls by the first value of the
+//metadata key name, such as an API key. Calls without it are identified
+//by the peer's IP
+func Metadata(name string) KeyFunc {
+	fallback := PeerIP()
+
+	return func(ctx context.Context, method string) string {
+		md, ok := metadata.FromIncomingContext(ctx)
+
+		if ok {
+			if values := md.Get(name); len(values) > 0 && values[0] != "" {
+				return values[0]
+			}
+		}
+
+		return fallback(ctx, method)
+	}
+}
+
+//Method is a KeyFunc that identifies calls by the method called,
+//which limits every client of a method together
+func Method() KeyFunc {
+	return func(ctx context.Context, method string) string {
+		return method
+	}
+}
+
+//Join is a KeyFunc that joins the keys of fns with a colon,
+//e.g Join(PeerIP(), Method()) limits each peer on each method
+func Join(fns ...KeyFunc) KeyFunc {
+	return func(ctx context.Context, method string) string {
+		keys := make([]string, len(fns))
+
+		for i, fn := range fns {
+			keys[i] = fn(ctx, method)
+		}
+
+		return strings.Join(keys, ":")
+	}
+}
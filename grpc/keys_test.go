@@ -0,0 +1,47 @@
+package grpc
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+func peerContext() context.Context {
+	return peer.NewContext(context.Background(), &peer.Peer{
+		Addr: &net.TCPAddr{IP: net.ParseIP("123.45.67.89"), Port: 50051},
+	})
+}
+
+func TestPeerIP(t *testing.T) {
+	if key := PeerIP()(peerContext(), "/api.Users/Get"); key != "123.45.67.89" {
+		t.Fatalf(`Keys differ.. Expected %s.. Got %s`, "123.45.67.89", key)
+	}
+
+	if key := PeerIP()(context.Background(), "/api.Users/Get"); key != "" {
+		t.Fatalf(`Expected an empty key without a peer.. Got %s`, key)
+	}
+}
+
+func TestMetadata(t *testing.T) {
+	ctx := metadata.NewIncomingContext(peerContext(), metadata.Pairs("x-api-key", "secret"))
+
+	if key := Metadata("x-api-key")(ctx, "/api.Users/Get"); key != "secret" {
+		t.Fatalf(`Keys differ.. Expected %s.. Got %s`, "secret", key)
+	}
+
+	//Calls without the metadata are identified by the peer
+	if key := Metadata("x-api-key")(peerContext(), "/api.Users/Get"); key != "123.45.67.89" {
+		t.Fatalf(`Keys differ.. Expected %s.. Got %s`, "123.45.67.89", key)
+	}
+}
+
+func TestJoin(t *testing.T) {
+	key := Join(PeerIP(), Method())(peerContext(), "/api.Users/Get")
+
+	if expected := "123.45.67.89:/api.Users/Get"; key != expected {
+		t.Fatalf(`Keys differ.. Expected %s.. Got %s`, expected, key)
+	}
+}
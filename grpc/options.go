@@ -0,0 +1,21 @@
+package grpc
+
+//Option configures an Interceptor
+type Option func(*Interceptor)
+
+//Key is an Option that sets how calls are identified.
+//It defaults to PeerIP
+func Key(fn KeyFunc) Option {
+	return func(i *Interceptor) {
+		i.key = fn
+	}
+}
+
+//PerMessage is an Option that throttles every message received on
+//a stream, on top of the stream being opened. A rate limited message
+//fails the stream with codes.ResourceExhausted
+func PerMessage(enabled bool) Option {
+	return func(i *Interceptor) {
+		i.perMessage = enabled
+	}
+}
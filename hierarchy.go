@@ -0,0 +1,145 @@
+package gottle
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+//LevelThrottler is what a Level needs from it's throttler.
+//OnecacheThrottler and Global implement it
+type LevelThrottler interface {
+	ClearKey(key string) error
+
+	//requestKey returns the key r is counted under by default
+	requestKey(r *http.Request) string
+
+	reserve(ctx context.Context, key string, n int) (*Reservation, error)
+}
+
+//Level is one of the limits of a Hierarchy
+type Level struct {
+	//Name identifies the level in what Take returns
+	Name string
+
+	//Key returns the key r is counted under on the level. Requests it
+	//returns an empty key for skip the level, such as anonymous ones on
+	//a per user level. A nil Key counts requests by IP, like Throttle
+	Key func(r *http.Request) string
+
+	//Throttler holds the limit of the level.
+	//Keys returned by Key are used as is, as with ThrottleKey
+	Throttler LevelThrottler
+}
+
+func (l Level) key(r *http.Request) string {
+	if l.Key == nil {
+		return l.Throttler.requestKey(r)
+	}
+
+	return l.Key(r)
+}
+
+func (t *OnecacheThrottler) requestKey(r *http.Request) string {
+	return t.keyGenerator(t.ipProvider.IP(r))
+}
+
+//Hierarchy limits requests at several levels at once, such as per user,
+//per organization and globally to protect the backend. A request is only
+//let through if every level allows it and it is only counted, on every
+//level, if it is let through
+type Hierarchy struct {
+	levels []Level
+}
+
+//NewHierarchy returns a Hierarchy that checks levels in order.
+//Putting the levels most likely to deny a request first, usually the
+//narrowest ones, keeps the hits that have to be given back to a minimum
+func NewHierarchy(levels ...Level) *Hierarchy {
+	return &Hierarchy{levels: append([]Level{}, levels...)}
+}
+
+//Throttle throttles r on every level
+func (h *Hierarchy) Throttle(r *http.Request) error {
+	return h.ThrottleN(r, defaultThrottledItemIncrement)
+}
+
+//ThrottleN throttles r, which costs n hits, on every level
+func (h *Hierarchy) ThrottleN(r *http.Request, n int) error {
+	_, err := h.Take(r, n)
+	return err
+}
+
+//Take records n hits for r on every level, in order. If a level denies
+//them, the hits already recorded on the levels before it are given back and
+//the name of the level is returned along with ErrClientIsRateLimited.
+//Hits that can't be given back stay counted on their level, the first error
+//doing so is then joined to ErrClientIsRateLimited, which errors.Is still finds.
+//Levels whose store fails are skipped and the first error is returned
+//if no level denies r. Others see the hits of a request that ends up
+//denied until they are given back, which errs on the side of limiting
+func (h *Hierarchy) Take(r *http.Request, n int) (string, error) {
+	if n < 1 {
+		return "", ErrInvalidCost
+	}
+
+	var taken []*Reservation
+	var storeErr error
+
+	for _, l := range h.levels {
+		key := l.key(r)
+
+		if key == "" {
+			continue
+		}
+
+		res, err := l.Throttler.reserve(r.Context(), key, n)
+
+		switch {
+		case err == ErrInvalidReservation:
+			//More hits than the level's limit are never let through
+		case err != nil:
+			if storeErr == nil {
+				storeErr = err
+			}
+
+			continue
+		case res.OK():
+			taken = append(taken, res)
+			continue
+		}
+
+		var cancelErr error
+
+		for _, res := range taken {
+			if err := res.Cancel(); err != nil && cancelErr == nil {
+				cancelErr = err
+			}
+		}
+
+		if cancelErr != nil {
+			return l.Name, errors.Join(ErrClientIsRateLimited, cancelErr)
+		}
+
+		return l.Name, ErrClientIsRateLimited
+	}
+
+	return "", storeErr
+}
+
+//Clear resets the throttle on r on every level
+func (h *Hierarchy) Clear(r *http.Request) error {
+	for _, l := range h.levels {
+		key := l.key(r)
+
+		if key == "" {
+			continue
+		}
+
+		if err := l.Throttler.ClearKey(key); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
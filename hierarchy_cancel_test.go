@@ -0,0 +1,56 @@
+package gottle_test
+
+//gottletest imports gottle, so tests making use of it live outside the package
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/adelowo/gottle"
+	"github.com/adelowo/gottle/gottletest"
+)
+
+func TestHierarchy_Take_cancelError(t *testing.T) {
+	store := gottletest.NewStore(nil)
+
+	user := gottle.NewOneCacheThrottler(
+		gottle.Store(store), gottle.ThrottleCondition(time.Minute, 5))
+
+	h := gottle.NewHierarchy(
+		gottle.Level{Name: "user", Key: func(r *http.Request) string { return r.Header.Get("X-User") },
+			Throttler: user},
+		gottle.Level{Name: "org", Key: func(r *http.Request) string { return "acme" },
+			Throttler: gottle.NewOneCacheThrottler(gottle.ThrottleCondition(time.Minute, 1))},
+	)
+
+	request := func(name string) *http.Request {
+		r := httptest.NewRequest(http.MethodGet, "/oops", nil)
+		r.Header.Set("X-User", name)
+		return r
+	}
+
+	if _, err := h.Take(request("ann"), 1); err != nil {
+		t.Fatalf(`An error occurred while taking the hits.. %v`, err)
+	}
+
+	expectedErr := errors.New("oops")
+
+	//The hit of bob is the only one left on the key, so it is deleted when given back
+	store.Fail(gottletest.OpDelete, expectedErr)
+
+	level, err := h.Take(request("bob"), 1)
+
+	if level != "org" || !errors.Is(err, gottle.ErrClientIsRateLimited) || !errors.Is(err, expectedErr) {
+		t.Fatalf(`Expected the org level to deny the request and the failure to give the hits back to be returned.. Got %q, %v`,
+			level, err)
+	}
+
+	store.Fail(gottletest.OpDelete, nil)
+
+	if status, _ := user.Status("bob"); status.Hits != 1 {
+		t.Fatalf(`Hits differ.. Expected %d.. Got %d`, 1, status.Hits)
+	}
+}
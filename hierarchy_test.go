@@ -0,0 +1,136 @@
+package gottle
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/adelowo/gottle/sharded"
+	"github.com/adelowo/onecache/memory"
+)
+
+var _ WeightedThrottler = &Hierarchy{}
+
+func header(name string) func(r *http.Request) string {
+	return func(r *http.Request) string {
+		return r.Header.Get(name)
+	}
+}
+
+func newHierarchyRequest(user, org string) *http.Request {
+	r := httptest.NewRequest(http.MethodGet, "/oops", nil)
+	r.Header.Set(xForwardedFor, "123.456.789.000")
+	r.Header.Set("X-User", user)
+	r.Header.Set("X-Org", org)
+
+	return r
+}
+
+func newHierarchy() (*Hierarchy, map[string]*OnecacheThrottler) {
+	throttlers := map[string]*OnecacheThrottler{
+		"user":   NewOneCacheThrottler(Store(sharded.New()), ThrottleCondition(time.Minute, 2)),
+		"org":    NewOneCacheThrottler(Store(sharded.New()), ThrottleCondition(time.Minute, 3)),
+		"global": NewOneCacheThrottler(ThrottleCondition(time.Minute, 4)),
+	}
+
+	return NewHierarchy(
+		Level{Name: "user", Key: header("X-User"), Throttler: throttlers["user"]},
+		Level{Name: "org", Key: header("X-Org"), Throttler: throttlers["org"]},
+		Level{Name: "global", Throttler: throttlers["global"]},
+	), throttlers
+}
+
+func TestHierarchy_Take(t *testing.T) {
+	h, throttlers := newHierarchy()
+
+	tt := []struct {
+		user, org string
+		level     string
+	}{
+		{"ann", "acme", ""},
+		{"ann", "acme", ""},
+		{"ann", "acme", "user"},
+		{"bob", "acme", ""},
+		{"cid", "acme", "org"},
+		{"dan", "", ""},
+		{"eve", "", "global"},
+	}
+
+	for i, v := range tt {
+		level, err := h.Take(newHierarchyRequest(v.user, v.org), 1)
+
+		if level != v.level {
+			t.Fatalf(`Levels differ for request %d.. Expected %q.. Got %q`, i+1, v.level, level)
+		}
+
+		if (level != "") != (err == ErrClientIsRateLimited) {
+			t.Fatalf(`Expected request %d to be denied by the level only.. Got %v`, i+1, err)
+		}
+	}
+
+	expected := map[string]map[string]int{
+		//cid was given back the hit taken before acme denied it
+		"user":   {"ann": 2, "bob": 1, "cid": 0, "dan": 1, "eve": 0},
+		"org":    {"acme": 3},
+		"global": {"123.456.789.000": 4},
+	}
+
+	for name, keys := range expected {
+		for key, hits := range keys {
+			if status, _ := throttlers[name].Status(key); status.Hits != hits {
+				t.Fatalf(`Hits of %s on the %s level differ.. Expected %d.. Got %d`,
+					key, name, hits, status.Hits)
+			}
+		}
+	}
+}
+
+func TestHierarchy_Middleware(t *testing.T) {
+	h, _ := newHierarchy()
+
+	handler := Middleware(h)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	for i, code := range []int{http.StatusNoContent, http.StatusNoContent, http.StatusTooManyRequests} {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, newHierarchyRequest("ann", "acme"))
+
+		if w.Code != code {
+			t.Fatalf(`Status codes differ for request %d.. Expected %d.. Got %d`, i+1, code, w.Code)
+		}
+	}
+
+	if err := h.Clear(newHierarchyRequest("ann", "acme")); err != nil {
+		t.Fatalf(`An error occurred while clearing the request.. %v`, err)
+	}
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, newHierarchyRequest("ann", "acme"))
+
+	if w.Code != http.StatusNoContent {
+		t.Fatalf(`Expected every level to have been cleared.. Got %d`, w.Code)
+	}
+}
+
+func TestHierarchy_storeError(t *testing.T) {
+	expectedErr := errors.New("oops")
+
+	h := NewHierarchy(
+		Level{Name: "broken", Throttler: NewOneCacheThrottler(
+			Store(&failingStore{memory.New(), expectedErr}))},
+		Level{Name: "user", Key: header("X-User"), Throttler: NewOneCacheThrottler(
+			ThrottleCondition(time.Minute, 1))},
+	)
+
+	if _, err := h.Take(newHierarchyRequest("ann", ""), 1); err != expectedErr {
+		t.Fatalf(`Errors differ.. Expected %v.. Got %v`, expectedErr, err)
+	}
+
+	//The levels after the broken one are still enforced
+	if level, _ := h.Take(newHierarchyRequest("ann", ""), 1); level != "user" {
+		t.Fatalf(`Expected the user level to deny the request.. Got %q`, level)
+	}
+}
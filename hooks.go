@@ -0,0 +1,156 @@
+package gottle
+
+import (
+	"sync"
+	"time"
+)
+
+//Event describes something that happened to a client of the throttler
+type Event struct {
+	//Name is the name of the throttler, as set by the Name option
+	Name string
+	Key  string
+
+	//IP is the IP of the client making the request. Clients throttled
+	//by key, with ThrottleKey or Reserve for example, have none
+	IP string
+
+	//Hits is the number of hits recorded for the client.
+	//It is not known, hence zero, for cleared and store error events
+	Hits  int
+	Limit int
+	Time  time.Time
+
+	//Shadow is true for events of a throttler in shadow mode.
+	//A limited event from one means the client would have been limited
+	Shadow bool
+}
+
+//StoreErrorEvent describes a failed operation on the store
+type StoreErrorEvent struct {
+	Event
+	Op  string
+	Err error
+}
+
+//hooks holds the functions registered on a throttler.
+//They are called synchronously, in the order they were registered
+type hooks struct {
+	mu         sync.RWMutex
+	onLimited  []func(Event)
+	onAllowed  []func(Event)
+	onCleared  []func(Event)
+	onStoreErr []func(StoreErrorEvent)
+}
+
+//fire calls the functions in fns with e. They are called once the lock
+//is released, so they can register hooks of their own
+func (h *hooks) fire(fns *[]func(Event), e Event) {
+	h.mu.RLock()
+	registered := *fns
+	h.mu.RUnlock()
+
+	//Registering appends past the end of the slice, never within it
+	for _, fn := range registered {
+		fn(e)
+	}
+}
+
+func (h *hooks) limited(e Event) {
+	if h != nil {
+		h.fire(&h.onLimited, e)
+	}
+}
+
+func (h *hooks) allowed(e Event) {
+	if h != nil {
+		h.fire(&h.onAllowed, e)
+	}
+}
+
+func (h *hooks) cleared(e Event) {
+	if h != nil {
+		h.fire(&h.onCleared, e)
+	}
+}
+
+func (h *hooks) storeError(e StoreErrorEvent) {
+	if h == nil {
+		return
+	}
+
+	h.mu.RLock()
+	registered := h.onStoreErr
+	h.mu.RUnlock()
+
+	for _, fn := range registered {
+		fn(e)
+	}
+}
+
+//registry returns the throttler's hooks.
+//Throttlers built as struct literals get theirs on first use
+func (t *OnecacheThrottler) registry() *hooks {
+	t.hooksOnce.Do(func() {
+		if t.hooks == nil {
+			t.hooks = new(hooks)
+		}
+	})
+
+	return t.hooks
+}
+
+func (t *OnecacheThrottler) event(c client, hits int) Event {
+	return Event{
+		Name:   t.name,
+		Key:    c.key,
+		IP:     c.ip,
+		Hits:   hits,
+		Limit:  c.limit,
+		Time:   t.now(),
+		Shadow: t.shadow,
+	}
+}
+
+//OnLimited registers fn to be called when a client becomes rate limited.
+//It fires once, on the request that uses up the client's last attempt,
+//rather than on every request denied after that.
+//Hooks run on the request's goroutine so slow work should be handed off
+func (t *OnecacheThrottler) OnLimited(fn func(Event)) {
+	h := t.registry()
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.onLimited = append(h.onLimited, fn)
+}
+
+//OnAllowed registers fn to be called for every request that is not rate limited
+func (t *OnecacheThrottler) OnAllowed(fn func(Event)) {
+	h := t.registry()
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.onAllowed = append(h.onAllowed, fn)
+}
+
+//OnCleared registers fn to be called when the throttle on a client is cleared
+func (t *OnecacheThrottler) OnCleared(fn func(Event)) {
+	h := t.registry()
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.onCleared = append(h.onCleared, fn)
+}
+
+//OnStoreError registers fn to be called when an operation on the store fails
+func (t *OnecacheThrottler) OnStoreError(fn func(StoreErrorEvent)) {
+	h := t.registry()
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.onStoreErr = append(h.onStoreErr, fn)
+}
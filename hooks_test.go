@@ -0,0 +1,207 @@
+package gottle
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/adelowo/onecache/memory"
+)
+
+func TestOnecacheThrottler_OnLimited(t *testing.T) {
+	r, teardown, err := setUp(t)
+	defer teardown()
+
+	if err != nil {
+		t.Fatalf("An error occurred while setting up the test ..%v", err)
+	}
+
+	r.Header.Set(xForwardedFor, "123.456.789.000")
+
+	throttler := NewOneCacheThrottler(
+		Name("login"),
+		KeyGenerator(func(ip string) string { return "login-" + ip }),
+		ThrottleCondition(time.Minute, 3))
+
+	var limited []Event
+	var allowed int
+
+	throttler.OnLimited(func(e Event) { limited = append(limited, e) })
+	throttler.OnAllowed(func(e Event) { allowed++ })
+
+	//Requests denied after the client got limited are not transitions
+	for i := 0; i < 6; i++ {
+		throttler.Throttle(r)
+	}
+
+	if allowed != 3 {
+		t.Fatalf(`Allowed events differ.. Expected %d.. Got %d`, 3, allowed)
+	}
+
+	if len(limited) != 1 {
+		t.Fatalf(`Expected a single limited event.. Got %d`, len(limited))
+	}
+
+	e := limited[0]
+
+	if e.Name != "login" || e.Key != "login-123.456.789.000" ||
+		e.IP != "123.456.789.000" || e.Hits != 3 || e.Limit != 3 || e.Time.IsZero() {
+		t.Fatalf(`The limited event was not filled in properly.. Got %+v`, e)
+	}
+
+	//Clearing the client lets it get limited again
+	var cleared int
+
+	throttler.OnCleared(func(e Event) { cleared++ })
+
+	if err := throttler.Clear(r); err != nil {
+		t.Fatalf(`An error occurred while clearing the request.. %v`, err)
+	}
+
+	for i := 0; i < 3; i++ {
+		throttler.Throttle(r)
+	}
+
+	if cleared != 1 || len(limited) != 2 {
+		t.Fatalf(`Expected 1 cleared and 2 limited events.. Got %d and %d`,
+			cleared, len(limited))
+	}
+}
+
+func TestOnecacheThrottler_OnStoreError(t *testing.T) {
+	r, teardown, err := setUp(t)
+	defer teardown()
+
+	if err != nil {
+		t.Fatalf("An error occurred while setting up the test ..%v", err)
+	}
+
+	r.Header.Set(xForwardedFor, "123.456.789.000")
+
+	expectedErr := errors.New("oops")
+
+	throttler := NewOneCacheThrottler(
+		Store(&failingStore{memory.New(), expectedErr}))
+
+	var events []StoreErrorEvent
+
+	throttler.OnStoreError(func(e StoreErrorEvent) { events = append(events, e) })
+
+	throttler.Throttle(r)
+
+	if len(events) != 1 {
+		t.Fatalf(`Expected a single store error event.. Got %d`, len(events))
+	}
+
+	if events[0].Op != OpSet || events[0].Err != expectedErr ||
+		events[0].IP != "123.456.789.000" {
+		t.Fatalf(`The store error event was not filled in properly.. Got %+v`, events[0])
+	}
+}
+
+func TestOnecacheThrottler_hooks_structLiteral(t *testing.T) {
+	r, teardown, err := setUp(t)
+	defer teardown()
+
+	if err != nil {
+		t.Fatalf("An error occurred while setting up the test ..%v", err)
+	}
+
+	r.Header.Set(xForwardedFor, "123.456.789.000")
+
+	throttler := &OnecacheThrottler{
+		ipProvider:   NewRealIP(),
+		keyGenerator: throttleKey,
+		store:        memory.New(),
+		maxRequests:  1,
+		interval:     time.Minute,
+	}
+
+	//Firing hooks before any has been registered must not panic
+	throttler.Throttle(r)
+
+	var limited int
+
+	throttler.OnLimited(func(e Event) { limited++ })
+
+	throttler.Clear(r)
+	throttler.Throttle(r)
+
+	if limited != 1 {
+		t.Fatalf(`Expected a single limited event.. Got %d`, limited)
+	}
+}
+
+func TestOnecacheThrottler_hooks_concurrent(t *testing.T) {
+	throttler := &OnecacheThrottler{
+		ipProvider:   NewRealIP(),
+		keyGenerator: throttleKey,
+		store:        memory.New(),
+		maxRequests:  10,
+		interval:     time.Minute,
+	}
+
+	var wg sync.WaitGroup
+
+	//The hooks of struct literals are set up by whoever gets there first
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+
+		go func() {
+			defer wg.Done()
+			throttler.OnAllowed(func(Event) {})
+		}()
+
+		go func() {
+			defer wg.Done()
+			throttler.ThrottleKey(context.Background(), "key")
+		}()
+	}
+
+	wg.Wait()
+}
+
+func TestOnecacheThrottler_hooks_registerFromHook(t *testing.T) {
+	throttler := NewOneCacheThrottler(ThrottleCondition(time.Minute, 10))
+
+	var allowed int
+
+	throttler.OnAllowed(func(Event) {
+		throttler.OnAllowed(func(Event) { allowed++ })
+	})
+
+	done := make(chan struct{})
+
+	go func() {
+		throttler.ThrottleKey(context.Background(), "key")
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second * 5):
+		t.Fatal(`Registering a hook from a hook is not supposed to deadlock`)
+	}
+
+	throttler.ThrottleKey(context.Background(), "key")
+
+	if allowed != 1 {
+		t.Fatalf(`Expected the hook registered by the first request to fire.. Got %d`, allowed)
+	}
+}
+
+func TestOnecacheThrottler_hooks_throttleKey(t *testing.T) {
+	throttler := NewOneCacheThrottler(ThrottleCondition(time.Minute, 10))
+
+	var event Event
+
+	throttler.OnAllowed(func(e Event) { event = e })
+
+	throttler.ThrottleKey(context.Background(), "key")
+
+	if event.Key != "key" || event.IP != "" {
+		t.Fatalf(`Expected the event of a key to have no IP.. Got %v`, event)
+	}
+}
@@ -1,6 +1,7 @@
 package gottle
 
 import (
+	"fmt"
 	"net"
 	"net/http"
 	"strings"
@@ -64,3 +65,102 @@ func (rip *RemoteIP) IP(r *http.Request) string {
 func NewRemoteIP() *RemoteIP {
 	return &RemoteIP{}
 }
+
+//TrustedProxies is an IPProvider that only believes the "X-Forwarded-For"
+//header of requests sent by one of it's proxies. The client is the
+//rightmost address in the header that is not a trusted proxy,
+//so clients can't pick their own IP by sending the header themselves
+type TrustedProxies struct {
+	proxies []*net.IPNet
+}
+
+//NewTrustedProxies returns a TrustedProxies that trusts the given
+//IP addresses and CIDR ranges, such as "10.0.0.0/8"
+func NewTrustedProxies(proxies ...string) (*TrustedProxies, error) {
+	nets, err := ParseNetworks(proxies...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &TrustedProxies{proxies: nets}, nil
+}
+
+//ParseNetworks parses IP addresses and CIDR ranges.
+//An address is turned into a range that holds only itself
+func ParseNetworks(addrs ...string) ([]*net.IPNet, error) {
+	nets := make([]*net.IPNet, 0, len(addrs))
+
+	for _, addr := range addrs {
+		if !strings.Contains(addr, "/") {
+			ip := net.ParseIP(addr)
+
+			if ip == nil {
+				return nil, fmt.Errorf("gottle: %q is not a valid IP address", addr)
+			}
+
+			bits := 8 * net.IPv6len
+
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+
+		_, n, err := net.ParseCIDR(addr)
+
+		if err != nil {
+			return nil, fmt.Errorf("gottle: %q is not a valid CIDR range", addr)
+		}
+
+		nets = append(nets, n)
+	}
+
+	return nets, nil
+}
+
+func (tp *TrustedProxies) trusted(addr string) bool {
+	ip := net.ParseIP(addr)
+
+	if ip == nil {
+		return false
+	}
+
+	for _, n := range tp.proxies {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+//IP returns the ip associated with the request
+func (tp *TrustedProxies) IP(r *http.Request) string {
+	ip := NewRemoteIP().IP(r)
+
+	if !tp.trusted(ip) {
+		return ip
+	}
+
+	hops := strings.Split(r.Header.Get(xForwardedFor), ",")
+
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+
+		if hop == "" {
+			continue
+		}
+
+		if !tp.trusted(hop) {
+			return hop
+		}
+
+		ip = hop
+	}
+
+	//Every hop is a proxy, the first one is as close to the client as it gets
+	return ip
+}
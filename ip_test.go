@@ -8,6 +8,7 @@ import (
 
 var _ IPProvider = &RealIP{}
 var _ IPProvider = &RemoteIP{}
+var _ IPProvider = &TrustedProxies{}
 
 func setUp(t *testing.T) (*http.Request, func(), error) {
 
@@ -77,3 +78,37 @@ func TestRemoteIP(t *testing.T) {
 		}
 	}
 }
+
+func TestTrustedProxies(t *testing.T) {
+	provider, err := NewTrustedProxies("10.0.0.0/8", "192.168.1.1")
+
+	if err != nil {
+		t.Fatalf(`An error occurred while parsing the proxies.. %v`, err)
+	}
+
+	cases := []struct {
+		remoteAddr, xff, expected string
+	}{
+		//Untrusted peers can't pick their IP
+		{"1.2.3.4:1234", "5.6.7.8", "1.2.3.4"},
+		{"10.0.0.1:1234", "5.6.7.8", "5.6.7.8"},
+		{"10.0.0.1:1234", "9.9.9.9, 5.6.7.8, 192.168.1.1", "5.6.7.8"},
+		{"10.0.0.1:1234", "", "10.0.0.1"},
+		{"10.0.0.1:1234", "192.168.1.1", "192.168.1.1"},
+	}
+
+	for _, v := range cases {
+		r := httptest.NewRequest(http.MethodGet, "/oops", nil)
+		r.RemoteAddr = v.remoteAddr
+		r.Header.Set(xForwardedFor, v.xff)
+
+		if actual := provider.IP(r); actual != v.expected {
+			t.Fatalf(`IPs differ for (%s, %s).. Expected %s.. Got %s`,
+				v.remoteAddr, v.xff, v.expected, actual)
+		}
+	}
+
+	if _, err := NewTrustedProxies("oops"); err == nil {
+		t.Fatal(`Expected an error for an invalid address`)
+	}
+}
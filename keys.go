@@ -0,0 +1,129 @@
+package gottle
+
+import (
+	"context"
+	"time"
+)
+
+//KeyStatus describes the state of a key in the throttler
+type KeyStatus struct {
+	Key       string `json:"key"`
+	Hits      int    `json:"hits"`
+	Limit     int    `json:"limit"`
+	Remaining int    `json:"remaining"`
+	Limited   bool   `json:"limited"`
+
+	//ResetAt is when the hits recorded for the key expire.
+	//It is the zero time for keys that have not been throttled
+	ResetAt time.Time `json:"reset_at"`
+}
+
+//clientOfKey returns a client for operations carried out
+//on a key directly, rather than for a request
+func (t *OnecacheThrottler) clientOfKey(key string) client {
+	c := client{ctx: context.Background(), key: key}
+	c.limit, c.interval = t.limitsFor(c)
+	t.windowed(&c)
+
+	return c
+}
+
+//Status returns the state of key.
+//Keys that have not been throttled are reported with no hits
+func (t *OnecacheThrottler) Status(key string) (KeyStatus, error) {
+	c := t.clientOfKey(key)
+
+	status := KeyStatus{
+		Key:       key,
+		Limit:     c.limit,
+		Remaining: c.limit,
+	}
+
+	item, ok, err := t.load(c)
+
+	if err != nil {
+		return status, err
+	}
+
+	if !ok {
+		return status, nil
+	}
+
+	status.Hits = item.Hits
+	status.Remaining = remaining(c, item.Hits)
+	status.Limited = t.limited(c, item)
+	status.ResetAt = c.resetAt(item.LastThrottledAt)
+
+	return status, nil
+}
+
+//ThrottleKey works like Throttle for a key rather than an HTTP request,
+//for clients that are identified some other way. The key is used as is,
+//as in Status, and ctx is the context of the call being throttled.
+//There is no IP, so the events and logs of the call have an empty one
+func (t *OnecacheThrottler) ThrottleKey(ctx context.Context, key string) error {
+	c := t.clientOfKey(key)
+	c.ctx = ctx
+
+	err := t.decide(c, defaultThrottledItemIncrement)
+
+	if t.candidate != nil {
+		cc := t.candidate.clientOfKey(candidateKeyPrefix + key)
+		cc.ctx = ctx
+
+		t.candidate.decide(cc, defaultThrottledItemIncrement)
+	}
+
+	return err
+}
+
+//ClearKey resets the throttle on key
+func (t *OnecacheThrottler) ClearKey(key string) error {
+	return t.clear(t.clientOfKey(key))
+}
+
+//Restore replaces the hits recorded for status.Key with status.Hits,
+//expiring them at status.ResetAt. It is the counterpart of Status,
+//meant for moving state between stores. Statuses that have already
+//expired clear the key instead
+func (t *OnecacheThrottler) Restore(status KeyStatus) error {
+	c := t.clientOfKey(status.Key)
+
+	ttl := status.ResetAt.Sub(t.now())
+
+	if status.Hits <= 0 || ttl <= 0 {
+		return t.clear(c)
+	}
+
+	return t.put(c, status.Hits, status.ResetAt.Add(-c.interval), ttl)
+}
+
+//put replaces the hits recorded for c with hits, last being the most
+//recent one, and expires them after ttl
+func (t *OnecacheThrottler) put(c client, hits int, last time.Time, ttl time.Duration) error {
+	if counter, ok := t.store.(Counter); ok {
+		//Incr adds to what is there
+		err := t.timed(c, OpDelete, func() error {
+			return t.store.Delete(c.entry())
+		})
+
+		if err != nil {
+			return err
+		}
+
+		return t.timed(c, OpIncr, func() error {
+			_, err := counter.Incr(c.entry(), hits, last, ttl)
+			return err
+		})
+	}
+
+	buf, err := EncodeGob(&throttledItem{Hits: hits, LastThrottledAt: last})
+
+	if err != nil {
+		return err
+	}
+
+	return t.timed(c, OpSet, func() error {
+		return t.store.Set(c.entry(), buf, ttl)
+	})
+}
@@ -0,0 +1,149 @@
+package gottle
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestOnecacheThrottler_Status(t *testing.T) {
+	r, teardown, err := setUp(t)
+	defer teardown()
+
+	if err != nil {
+		t.Fatalf("An error occurred while setting up the test ..%v", err)
+	}
+
+	r.Header.Set(xForwardedFor, "123.456.789.000")
+
+	now := time.Date(2017, time.March, 1, 10, 0, 0, 0, time.UTC)
+
+	throttler := NewOneCacheThrottler(
+		Clock(fixedClock{now}),
+		ThrottleCondition(time.Minute, 3))
+
+	status, err := throttler.Status("123.456.789.000")
+
+	if err != nil {
+		t.Fatalf(`An error occurred while fetching the status.. %v`, err)
+	}
+
+	expected := KeyStatus{Key: "123.456.789.000", Limit: 3, Remaining: 3}
+
+	if status != expected {
+		t.Fatalf(`Status differs.. Expected %+v.. Got %+v`, expected, status)
+	}
+
+	for i := 0; i < 3; i++ {
+		throttler.Throttle(r)
+	}
+
+	status, err = throttler.Status("123.456.789.000")
+
+	if err != nil {
+		t.Fatalf(`An error occurred while fetching the status.. %v`, err)
+	}
+
+	expected = KeyStatus{
+		Key:       "123.456.789.000",
+		Hits:      3,
+		Limit:     3,
+		Remaining: 0,
+		Limited:   true,
+		ResetAt:   now.Add(time.Minute),
+	}
+
+	if status != expected {
+		t.Fatalf(`Status differs.. Expected %+v.. Got %+v`, expected, status)
+	}
+}
+
+func TestOnecacheThrottler_ClearKey(t *testing.T) {
+	r, teardown, err := setUp(t)
+	defer teardown()
+
+	if err != nil {
+		t.Fatalf("An error occurred while setting up the test ..%v", err)
+	}
+
+	r.Header.Set(xForwardedFor, "123.456.789.000")
+
+	throttler := NewOneCacheThrottler(ThrottleCondition(time.Minute, 1))
+
+	throttler.Throttle(r)
+
+	if !throttler.IsRateLimited(r) {
+		t.Fatal(`The request is supposed to be rate limited`)
+	}
+
+	if err := throttler.ClearKey("123.456.789.000"); err != nil {
+		t.Fatalf(`An error occurred while clearing the key.. %v`, err)
+	}
+
+	if throttler.IsRateLimited(r) {
+		t.Fatal(`The request is not supposed to be rate limited after it's key was cleared`)
+	}
+}
+
+func TestOnecacheThrottler_Restore(t *testing.T) {
+	now := time.Date(2017, time.March, 1, 10, 0, 0, 0, time.UTC)
+
+	throttler := NewOneCacheThrottler(
+		Clock(fixedClock{now}),
+		ThrottleCondition(time.Minute, 3))
+
+	expected := KeyStatus{
+		Key:       "123.456.789.000",
+		Hits:      3,
+		Limit:     3,
+		Remaining: 0,
+		Limited:   true,
+		ResetAt:   now.Add(time.Second * 30),
+	}
+
+	if err := throttler.Restore(expected); err != nil {
+		t.Fatalf(`An error occurred while restoring the key.. %v`, err)
+	}
+
+	status, err := throttler.Status(expected.Key)
+
+	if err != nil {
+		t.Fatalf(`An error occurred while fetching the status.. %v`, err)
+	}
+
+	if status != expected {
+		t.Fatalf(`Status differs.. Expected %+v.. Got %+v`, expected, status)
+	}
+
+	//An expired status clears the key
+	expected.ResetAt = now.Add(-time.Second)
+
+	if err := throttler.Restore(expected); err != nil {
+		t.Fatalf(`An error occurred while restoring the key.. %v`, err)
+	}
+
+	if status, _ := throttler.Status(expected.Key); status.Hits != 0 {
+		t.Fatalf(`Expected the key to have been cleared.. Got %+v`, status)
+	}
+}
+
+func TestOnecacheThrottler_ThrottleKey(t *testing.T) {
+	throttler := NewOneCacheThrottler(
+		ThrottleCondition(time.Minute, 2),
+		Candidate(ThrottleCondition(time.Minute, 1)))
+
+	for i := 0; i < 2; i++ {
+		if err := throttler.ThrottleKey(context.Background(), "/api.Users/Get"); err != nil {
+			t.Fatalf(`An error occurred while throttling the key.. %v`, err)
+		}
+	}
+
+	if err := throttler.ThrottleKey(context.Background(), "/api.Users/Get"); err != ErrClientIsRateLimited {
+		t.Fatalf(`Errors differ.. Expected %v.. Got %v`, ErrClientIsRateLimited, err)
+	}
+
+	//The candidate stopped counting at it's limit
+	if status, _ := throttler.Status("candidate:/api.Users/Get"); status.Hits != 1 {
+		t.Fatalf(`Expected the candidate to have counted the key.. Got %+v`, status)
+	}
+}
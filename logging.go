@@ -0,0 +1,138 @@
+package gottle
+
+import (
+	"log/slog"
+	"sync/atomic"
+)
+
+const defaultDeniedSampling = 100
+
+//LogLevels sets the level each kind of message is logged at.
+//Levels that are left nil keep their default
+type LogLevels struct {
+	//StoreError is used for failed operations on the store.
+	//It defaults to slog.LevelError
+	StoreError slog.Leveler
+
+	//Decode is used for items in the store that can't be decoded.
+	//It defaults to slog.LevelError
+	Decode slog.Leveler
+
+	//Limited is used when a client becomes rate limited.
+	//It defaults to slog.LevelWarn
+	Limited slog.Leveler
+
+	//Denied is used for requests denied because the client is rate limited.
+	//These are sampled as there can be a lot of them. It defaults to slog.LevelDebug
+	Denied slog.Leveler
+}
+
+//merge returns l with the levels set in levels in place of it's own
+func (l LogLevels) merge(levels LogLevels) LogLevels {
+	if levels.StoreError != nil {
+		l.StoreError = levels.StoreError
+	}
+
+	if levels.Decode != nil {
+		l.Decode = levels.Decode
+	}
+
+	if levels.Limited != nil {
+		l.Limited = levels.Limited
+	}
+
+	if levels.Denied != nil {
+		l.Denied = levels.Denied
+	}
+
+	return l
+}
+
+var defaultLogLevels = LogLevels{
+	StoreError: slog.LevelError,
+	Decode:     slog.LevelError,
+	Limited:    slog.LevelWarn,
+	Denied:     slog.LevelDebug,
+}
+
+//logger logs what the throttler runs into.
+//Nothing is logged until it has a *slog.Logger
+type logger struct {
+	l        *slog.Logger
+	levels   LogLevels
+	sampling uint64
+	denied   atomic.Uint64
+}
+
+//logging returns the throttler's logger, creating it with the defaults if needed
+func (t *OnecacheThrottler) logging() *logger {
+	if t.logger == nil {
+		t.logger = &logger{levels: defaultLogLevels, sampling: defaultDeniedSampling}
+	}
+
+	return t.logger
+}
+
+func (t *OnecacheThrottler) logs() bool {
+	return t.logger != nil && t.logger.l != nil
+}
+
+func (t *OnecacheThrottler) attrs(c client, hits int) []any {
+	attrs := []any{
+		slog.String("limiter", t.name),
+		slog.String("key", c.key),
+		slog.String("ip", c.ip),
+		slog.Int("hits", hits),
+		slog.Int("limit", c.limit),
+		slog.Duration("interval", c.interval),
+	}
+
+	if t.shadow {
+		attrs = append(attrs, slog.Bool("shadow", true))
+	}
+
+	return attrs
+}
+
+func (t *OnecacheThrottler) logStoreError(c client, op string, err error) {
+	if !t.logs() {
+		return
+	}
+
+	t.logger.l.Log(c.ctx, t.logger.levels.StoreError.Level(), "gottle: store operation failed",
+		append(t.attrs(c, 0), slog.String("op", op), slog.Any("error", err))...)
+}
+
+func (t *OnecacheThrottler) logDecodeError(c client, err error) {
+	if !t.logs() {
+		return
+	}
+
+	t.logger.l.Log(c.ctx, t.logger.levels.Decode.Level(), "gottle: could not decode the stored item",
+		append(t.attrs(c, 0), slog.Any("error", err))...)
+}
+
+func (t *OnecacheThrottler) logLimited(c client, hits int) {
+	if !t.logs() {
+		return
+	}
+
+	t.logger.l.Log(c.ctx, t.logger.levels.Limited.Level(), "gottle: client is now rate limited",
+		t.attrs(c, hits)...)
+}
+
+//logDenied logs one in every sampling denied requests
+func (t *OnecacheThrottler) logDenied(c client, hits int) {
+	if !t.logs() {
+		return
+	}
+
+	n := t.logger.denied.Add(1)
+
+	if t.logger.sampling > 1 && (n-1)%t.logger.sampling != 0 {
+		return
+	}
+
+	t.logger.l.Log(c.ctx, t.logger.levels.Denied.Level(), "gottle: request denied",
+		append(t.attrs(c, hits), slog.Uint64("denied", n))...)
+}
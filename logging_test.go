@@ -0,0 +1,147 @@
+package gottle
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/adelowo/onecache/memory"
+)
+
+//recordingHandler is a slog.Handler that keeps every record it handles
+type recordingHandler struct {
+	mu      sync.Mutex
+	records []slog.Record
+}
+
+func (h *recordingHandler) Enabled(context.Context, slog.Level) bool {
+	return true
+}
+
+func (h *recordingHandler) Handle(_ context.Context, r slog.Record) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.records = append(h.records, r)
+	return nil
+}
+
+func (h *recordingHandler) WithAttrs([]slog.Attr) slog.Handler {
+	return h
+}
+
+func (h *recordingHandler) WithGroup(string) slog.Handler {
+	return h
+}
+
+func (h *recordingHandler) count(msg string, level slog.Level) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	var n int
+
+	for _, r := range h.records {
+		if r.Message == msg && r.Level == level {
+			n++
+		}
+	}
+
+	return n
+}
+
+func TestOnecacheThrottler_Logger(t *testing.T) {
+	r, teardown, err := setUp(t)
+	defer teardown()
+
+	if err != nil {
+		t.Fatalf("An error occurred while setting up the test ..%v", err)
+	}
+
+	r.Header.Set(xForwardedFor, "123.456.789.000")
+
+	handler := new(recordingHandler)
+
+	throttler := NewOneCacheThrottler(
+		Logger(slog.New(handler)),
+		LogDeniedSampling(2),
+		ThrottleCondition(time.Minute, 2))
+
+	//2 allowed, then 5 denied
+	for i := 0; i < 7; i++ {
+		throttler.Throttle(r)
+	}
+
+	if n := handler.count("gottle: client is now rate limited", slog.LevelWarn); n != 1 {
+		t.Fatalf(`Expected the lockout to be logged once.. Got %d`, n)
+	}
+
+	//The 1st, 3rd and 5th denied requests
+	if n := handler.count("gottle: request denied", slog.LevelDebug); n != 3 {
+		t.Fatalf(`Expected %d denied requests to be logged.. Got %d`, 3, n)
+	}
+
+	var attrs = make(map[string]slog.Value)
+
+	handler.records[0].Attrs(func(a slog.Attr) bool {
+		attrs[a.Key] = a.Value
+		return true
+	})
+
+	if attrs["ip"].String() != "123.456.789.000" || attrs["hits"].Int64() != 2 ||
+		attrs["interval"].Duration() != time.Minute {
+		t.Fatalf(`The lockout was not logged with the client's details.. Got %v`, attrs)
+	}
+}
+
+func TestOnecacheThrottler_Logger_errors(t *testing.T) {
+	r, teardown, err := setUp(t)
+	defer teardown()
+
+	if err != nil {
+		t.Fatalf("An error occurred while setting up the test ..%v", err)
+	}
+
+	r.Header.Set(xForwardedFor, "123.456.789.000")
+
+	handler := new(recordingHandler)
+
+	store := &failingStore{memory.New(), errors.New("oops")}
+
+	throttler := NewOneCacheThrottler(
+		Store(store),
+		Logger(slog.New(handler)),
+		LoggingLevels(LogLevels{Decode: slog.LevelWarn}))
+
+	throttler.Throttle(r)
+
+	if n := handler.count("gottle: store operation failed", slog.LevelError); n != 1 {
+		t.Fatalf(`Expected the store error to be logged.. Got %d`, n)
+	}
+
+	//An item that isn't gob encoded can't be decoded
+	store.InMemoryStore.Set("123.456.789.000", []byte("oops"), time.Minute)
+
+	if throttler.IsRateLimited(r) {
+		t.Fatal(`A client whose item can't be decoded is not rate limited`)
+	}
+
+	if n := handler.count("gottle: could not decode the stored item", slog.LevelWarn); n != 1 {
+		t.Fatalf(`Expected the decode failure to be logged at the configured level.. Got %d`, n)
+	}
+}
+
+func TestOnecacheThrottler_LoggingLevels_info(t *testing.T) {
+	throttler := NewOneCacheThrottler(
+		LoggingLevels(LogLevels{Denied: slog.LevelInfo}))
+
+	expected := defaultLogLevels
+	expected.Denied = slog.LevelInfo
+
+	if !reflect.DeepEqual(expected, throttler.logger.levels) {
+		t.Fatalf(`Levels differ.. Expected %v.. Got %v`, expected, throttler.logger.levels)
+	}
+}
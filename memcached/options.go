@@ -0,0 +1,21 @@
+package memcached
+
+import "github.com/adelowo/gottle"
+
+//Option configures a Store
+type Option func(*Store)
+
+//Prefix is an Option that sets the prefix added to every key.
+//It defaults to "gottle:"
+func Prefix(prefix string) Option {
+	return func(s *Store) {
+		s.prefix = prefix
+	}
+}
+
+//Clock is an Option that sets the clock windows are aligned to
+func Clock(clock gottle.TimeProvider) Option {
+	return func(s *Store) {
+		s.clock = clock
+	}
+}
@@ -0,0 +1,233 @@
+package memcached
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type fakeItem struct {
+	value   []byte
+	flags   uint32
+	expires time.Time
+	cas     uint64
+}
+
+//fakeServer is an in-process memcached speaking enough of the
+//text protocol for the commands gomemcache sends
+type fakeServer struct {
+	listener net.Listener
+
+	mu    sync.Mutex
+	items map[string]*fakeItem
+	cas   uint64
+}
+
+func newFakeServer(t *testing.T) *fakeServer {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+
+	if err != nil {
+		t.Fatalf(`An error occurred while starting the fake server.. %v`, err)
+	}
+
+	s := &fakeServer{listener: l, items: make(map[string]*fakeItem)}
+
+	go s.serve()
+
+	t.Cleanup(func() { l.Close() })
+
+	return s
+}
+
+func (s *fakeServer) Addr() string {
+	return s.listener.Addr().String()
+}
+
+func (s *fakeServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+
+		if err != nil {
+			return
+		}
+
+		go s.handle(conn)
+	}
+}
+
+func (s *fakeServer) handle(conn net.Conn) {
+	defer conn.Close()
+
+	rw := bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn))
+
+	for {
+		line, err := rw.ReadString('\n')
+
+		if err != nil {
+			return
+		}
+
+		fields := strings.Fields(line)
+
+		if len(fields) == 0 {
+			continue
+		}
+
+		if err := s.dispatch(rw, fields); err != nil {
+			return
+		}
+
+		if err := rw.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+func (s *fakeServer) dispatch(rw *bufio.ReadWriter, fields []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch fields[0] {
+	case "get", "gets":
+		for _, key := range fields[1:] {
+			if item := s.live(key); item != nil {
+				fmt.Fprintf(rw, "VALUE %s %d %d %d\r\n%s\r\n",
+					key, item.flags, len(item.value), item.cas, item.value)
+			}
+		}
+
+		_, err := io.WriteString(rw, "END\r\n")
+		return err
+
+	case "set", "add", "cas":
+		flags, _ := strconv.ParseUint(fields[2], 10, 32)
+		exptime, _ := strconv.Atoi(fields[3])
+		size, _ := strconv.Atoi(fields[4])
+
+		data := make([]byte, size+2)
+
+		if _, err := io.ReadFull(rw, data); err != nil {
+			return err
+		}
+
+		if fields[0] == "add" && s.live(fields[1]) != nil {
+			_, err := io.WriteString(rw, "NOT_STORED\r\n")
+			return err
+		}
+
+		if fields[0] == "cas" {
+			item := s.live(fields[1])
+
+			if item == nil {
+				_, err := io.WriteString(rw, "NOT_FOUND\r\n")
+				return err
+			}
+
+			if unique, _ := strconv.ParseUint(fields[5], 10, 64); unique != item.cas {
+				_, err := io.WriteString(rw, "EXISTS\r\n")
+				return err
+			}
+		}
+
+		s.cas++
+
+		item := &fakeItem{value: data[:size], flags: uint32(flags), cas: s.cas}
+
+		//Like memcached, anything over 30 days is a unix timestamp
+		switch {
+		case exptime > maxRelativeExpiration:
+			item.expires = time.Unix(int64(exptime), 0)
+		case exptime > 0:
+			item.expires = time.Now().Add(time.Duration(exptime) * time.Second)
+		}
+
+		s.items[fields[1]] = item
+
+		_, err := io.WriteString(rw, "STORED\r\n")
+		return err
+
+	case "incr", "decr":
+		item := s.live(fields[1])
+
+		if item == nil {
+			_, err := io.WriteString(rw, "NOT_FOUND\r\n")
+			return err
+		}
+
+		delta, _ := strconv.ParseUint(fields[2], 10, 64)
+		val, _ := strconv.ParseUint(strings.TrimSpace(string(item.value)), 10, 64)
+
+		if fields[0] == "incr" {
+			val += delta
+		} else if delta > val {
+			val = 0
+		} else {
+			val -= delta
+		}
+
+		//Like memcached, values that get shorter are padded with spaces
+		value := strconv.FormatUint(val, 10)
+
+		if len(value) < len(item.value) {
+			value += strings.Repeat(" ", len(item.value)-len(value))
+		}
+
+		s.cas++
+
+		item.value = []byte(value)
+		item.cas = s.cas
+
+		_, err := fmt.Fprintf(rw, "%d\r\n", val)
+		return err
+
+	case "delete":
+		if s.live(fields[1]) == nil {
+			_, err := io.WriteString(rw, "NOT_FOUND\r\n")
+			return err
+		}
+
+		delete(s.items, fields[1])
+
+		_, err := io.WriteString(rw, "DELETED\r\n")
+		return err
+
+	case "flush_all":
+		s.items = make(map[string]*fakeItem)
+
+		_, err := io.WriteString(rw, "OK\r\n")
+		return err
+	}
+
+	_, err := io.WriteString(rw, "ERROR\r\n")
+	return err
+}
+
+//live returns the item stored under key if it has not expired
+func (s *fakeServer) live(key string) *fakeItem {
+	item, ok := s.items[key]
+
+	if !ok {
+		return nil
+	}
+
+	if !item.expires.IsZero() && time.Now().After(item.expires) {
+		delete(s.items, key)
+		return nil
+	}
+
+	return item
+}
+
+//has checks if the server holds key, without going through the protocol
+func (s *fakeServer) has(key string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.live(key) != nil
+}
@@ -0,0 +1,288 @@
+//Package memcached provides a memcached backed store for gottle.
+//
+//Counters are fixed windows. Every window gets it's own key, created
+//with add and bumped with incr, so hits are never read, modified and
+//written back the way gob encoded items are
+package memcached
+
+import (
+	"bytes"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/adelowo/gottle"
+	"github.com/adelowo/onecache"
+	"github.com/bradfitz/gomemcache/memcache"
+)
+
+const defaultPrefix = "gottle:"
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+//Store is a memcached backed implementation of onecache.Store,
+//gottle.Counter, gottle.LimitCounter and gottle.Decrementer
+type Store struct {
+	client *memcache.Client
+	window time.Duration
+	prefix string
+	clock  gottle.TimeProvider
+}
+
+//New returns a Store that counts hits in fixed windows of the given length.
+//The window should match the interval of the throttler making use of the store.
+//Windows are aligned to the store's clock rather than to the first hit,
+//so every instance sharing the server agrees on where a window starts
+func New(client *memcache.Client, window time.Duration, opts ...Option) *Store {
+	s := &Store{
+		client: client,
+		window: window,
+		prefix: defaultPrefix,
+		clock:  systemClock{},
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+func (s *Store) key(key string) string {
+	return s.prefix + key
+}
+
+//bucket returns the key of the current window for key and when that window started
+func (s *Store) bucket(key string) (string, time.Time) {
+	now := s.clock.Now()
+	start := now.Truncate(s.window)
+
+	return s.key(key) + ":" + strconv.FormatInt(start.Unix(), 10), start
+}
+
+//maxRelativeExpiration is the longest expiration, in seconds, memcached
+//takes as relative. Anything longer is read as a unix timestamp
+const maxRelativeExpiration = 60 * 60 * 24 * 30
+
+//expiration converts d to the seconds memcached expects,
+//rounding up so items never expire early. Durations over 30 days
+//are turned into the unix timestamp they end at
+func expiration(d time.Duration) int32 {
+	secs := int64(d / time.Second)
+
+	if d%time.Second != 0 {
+		secs++
+	}
+
+	if secs <= maxRelativeExpiration {
+		return int32(secs)
+	}
+
+	return int32(min(time.Now().Unix()+secs, math.MaxInt32))
+}
+
+//Set stores data under key
+func (s *Store) Set(key string, data []byte, expires time.Duration) error {
+	return s.client.Set(&memcache.Item{
+		Key:        s.key(key),
+		Value:      data,
+		Expiration: expiration(expires),
+	})
+}
+
+//Get fetches the data stored under key.
+//If key is a counter, the hits in the current window are returned
+//as a decimal string
+func (s *Store) Get(key string) ([]byte, error) {
+	bucket, _ := s.bucket(key)
+
+	items, err := s.client.GetMulti([]string{s.key(key), bucket})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if item, ok := items[s.key(key)]; ok {
+		return item.Value, nil
+	}
+
+	if item, ok := items[bucket]; ok {
+		return bytes.TrimSpace(item.Value), nil
+	}
+
+	return nil, onecache.ErrCacheMiss
+}
+
+//Delete removes key, including the counter for the current window
+func (s *Store) Delete(key string) error {
+	bucket, _ := s.bucket(key)
+
+	for _, k := range []string{s.key(key), bucket} {
+		if err := s.client.Delete(k); err != nil && err != memcache.ErrCacheMiss {
+			return err
+		}
+	}
+
+	return nil
+}
+
+//Flush removes every item on the server, including those not set by gottle
+func (s *Store) Flush() error {
+	return s.client.DeleteAll()
+}
+
+//Has checks if key exists or has hits in the current window
+func (s *Store) Has(key string) bool {
+	_, err := s.Get(key)
+	return err == nil
+}
+
+//Window returns how long the store's windows are
+func (s *Store) Window() time.Duration {
+	return s.window
+}
+
+//Incr adds n hits to the current window of key.
+//now and ttl are ignored in favour of the store's clock and window
+func (s *Store) Incr(key string, n int, now time.Time, ttl time.Duration) (int, error) {
+	bucket, _ := s.bucket(key)
+
+	hits, err := s.incr(bucket, n)
+
+	return int(hits), err
+}
+
+func (s *Store) incr(bucket string, n int) (uint64, error) {
+	//A window that is already there makes add fail, which is fine
+	err := s.client.Add(&memcache.Item{
+		Key:        bucket,
+		Value:      []byte("0"),
+		Expiration: expiration(s.window),
+	})
+
+	if err != nil && err != memcache.ErrNotStored {
+		return 0, err
+	}
+
+	return s.client.Increment(bucket, uint64(n))
+}
+
+//IncrUnder adds n hits to the current window of key
+//unless they would take it past limit.
+//The window is read with gets and written back with cas, starting over if
+//another caller changed it in between, so the hits of concurrent callers are
+//neither lost nor counted against others they don't go along with. That takes
+//two round trips, more under contention. Windows still start by each
+//instance's own clock, so instances whose clocks disagree count in
+//different windows around the boundaries
+func (s *Store) IncrUnder(key string, n, limit int, now time.Time, ttl time.Duration) (int, bool, error) {
+	bucket, _ := s.bucket(key)
+
+	for {
+		item, err := s.client.Get(bucket)
+
+		if err == memcache.ErrCacheMiss {
+			if n > limit {
+				return 0, false, nil
+			}
+
+			err = s.client.Add(&memcache.Item{
+				Key:        bucket,
+				Value:      []byte(strconv.Itoa(n)),
+				Expiration: expiration(s.window),
+			})
+
+			//Another caller started the window first
+			if err == memcache.ErrNotStored {
+				continue
+			}
+
+			if err != nil {
+				return 0, false, err
+			}
+
+			return n, true, nil
+		}
+
+		if err != nil {
+			return 0, false, err
+		}
+
+		hits, err := parseHits(item.Value)
+
+		if err != nil {
+			return 0, false, err
+		}
+
+		if n > limit || hits+n > limit {
+			return hits, false, nil
+		}
+
+		item.Value = []byte(strconv.Itoa(hits + n))
+
+		//cas sets the expiration too, the window can't outlive it's key by more than one
+		item.Expiration = expiration(s.window)
+
+		err = s.client.CompareAndSwap(item)
+
+		if err == memcache.ErrCASConflict || err == memcache.ErrNotStored || err == memcache.ErrCacheMiss {
+			continue
+		}
+
+		if err != nil {
+			return 0, false, err
+		}
+
+		return hits + n, true, nil
+	}
+}
+
+//parseHits reads the hits of a window.
+//memcached pads values that decr made shorter with spaces
+func parseHits(value []byte) (int, error) {
+	return strconv.Atoi(strings.TrimSpace(string(value)))
+}
+
+//Decr takes n hits back from the current window of key
+//and returns the hits left. memcached never goes below zero
+func (s *Store) Decr(key string, n int) (int, error) {
+	bucket, _ := s.bucket(key)
+
+	hits, err := s.client.Decrement(bucket, uint64(n))
+
+	if err == memcache.ErrCacheMiss {
+		return 0, nil
+	}
+
+	return int(hits), err
+}
+
+//Count returns the hits in the current window of key.
+//memcached doesn't keep track of the most recent hit,
+//so the start of the window is reported in it's place
+func (s *Store) Count(key string) (int, time.Time, error) {
+	bucket, start := s.bucket(key)
+
+	item, err := s.client.Get(bucket)
+
+	if err == memcache.ErrCacheMiss {
+		return 0, time.Time{}, onecache.ErrCacheMiss
+	}
+
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+
+	hits, err := parseHits(item.Value)
+
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+
+	return hits, start, nil
+}
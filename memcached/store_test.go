@@ -0,0 +1,265 @@
+package memcached
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/adelowo/gottle"
+	"github.com/adelowo/gottle/gottletest"
+	"github.com/adelowo/onecache"
+	"github.com/bradfitz/gomemcache/memcache"
+)
+
+var _ onecache.Store = &Store{}
+var _ gottle.Counter = &Store{}
+var _ gottle.LimitCounter = &Store{}
+var _ gottle.Decrementer = &Store{}
+var _ gottle.WindowedStore = &Store{}
+
+func setUp(t *testing.T, window time.Duration, opts ...Option) (*Store, *fakeServer) {
+	server := newFakeServer(t)
+
+	return New(memcache.New(server.Addr()), window, opts...), server
+}
+
+func TestStore_SetGet(t *testing.T) {
+	store, server := setUp(t, time.Minute)
+
+	if err := store.Set("key", []byte("value"), time.Minute); err != nil {
+		t.Fatalf(`An error occurred while setting the key.. %v`, err)
+	}
+
+	if !server.has(defaultPrefix + "key") {
+		t.Fatal(`Expected the key to have been prefixed`)
+	}
+
+	buf, err := store.Get("key")
+
+	if err != nil || string(buf) != "value" {
+		t.Fatalf(`Data differs.. Expected %s.. Got %s, %v`, "value", buf, err)
+	}
+
+	if err := store.Delete("key"); err != nil {
+		t.Fatalf(`An error occurred while deleting the key.. %v`, err)
+	}
+
+	if store.Has("key") {
+		t.Fatal(`The key is not supposed to exist after it has been deleted`)
+	}
+
+	if _, err := store.Get("key"); err != onecache.ErrCacheMiss {
+		t.Fatalf(`Errors differ.. Expected %v.. Got %v`, onecache.ErrCacheMiss, err)
+	}
+}
+
+func TestStore_Incr(t *testing.T) {
+	start := time.Date(2017, time.March, 1, 10, 0, 0, 0, time.UTC)
+
+	clock := gottletest.NewClock(start.Add(time.Second * 10))
+
+	store, server := setUp(t, time.Minute, Clock(clock))
+
+	for i := 1; i <= 3; i++ {
+		hits, err := store.Incr("key", 2, clock.Now(), time.Minute)
+
+		if err != nil || hits != i*2 {
+			t.Fatalf(`Hits differ.. Expected %d.. Got %d, %v`, i*2, hits, err)
+		}
+	}
+
+	bucket := defaultPrefix + "key:1488362400"
+
+	if !server.has(bucket) {
+		t.Fatalf(`Expected the hits to have been stored under %s`, bucket)
+	}
+
+	hits, windowStart, err := store.Count("key")
+
+	if err != nil || hits != 6 || !windowStart.Equal(start) {
+		t.Fatalf(`Count differs.. Expected (%d, %v).. Got (%d, %v, %v)`,
+			6, start, hits, windowStart, err)
+	}
+
+	//The next window starts from scratch
+	clock.Advance(time.Minute)
+
+	if _, _, err := store.Count("key"); err != onecache.ErrCacheMiss {
+		t.Fatalf(`Errors differ.. Expected %v.. Got %v`, onecache.ErrCacheMiss, err)
+	}
+
+	if hits, _ := store.Incr("key", 1, clock.Now(), time.Minute); hits != 1 {
+		t.Fatalf(`Hits differ.. Expected %d.. Got %d`, 1, hits)
+	}
+}
+
+func TestStore_IncrUnder(t *testing.T) {
+	store, _ := setUp(t, time.Minute, Clock(gottletest.NewClock(time.Now())))
+
+	now := time.Now()
+
+	for i := 1; i <= 3; i++ {
+		hits, added, err := store.IncrUnder("key", 1, 3, now, time.Minute)
+
+		if err != nil || !added || hits != i {
+			t.Fatalf(`Expected hit %d to be added.. Got %d, %v, %v`, i, hits, added, err)
+		}
+	}
+
+	hits, added, err := store.IncrUnder("key", 1, 3, now, time.Minute)
+
+	if err != nil || added || hits != 3 {
+		t.Fatalf(`Expected the limit to be enforced.. Got %d, %v, %v`, hits, added, err)
+	}
+}
+
+func TestStore_IncrUnder_cost(t *testing.T) {
+	store, _ := setUp(t, time.Minute, Clock(gottletest.NewClock(time.Now())))
+
+	now := time.Now()
+
+	if hits, added, err := store.IncrUnder("key", 7, 10, now, time.Minute); err != nil || !added || hits != 7 {
+		t.Fatalf(`Expected the hits to be added.. Got %d, %v, %v`, hits, added, err)
+	}
+
+	//Hits that would go past the limit are not added at all
+	if hits, added, err := store.IncrUnder("key", 4, 10, now, time.Minute); err != nil || added || hits != 7 {
+		t.Fatalf(`Expected the limit to be enforced.. Got %d, %v, %v`, hits, added, err)
+	}
+
+	if hits, added, err := store.IncrUnder("key", 3, 10, now, time.Minute); err != nil || !added || hits != 10 {
+		t.Fatalf(`Expected the hits to be added.. Got %d, %v, %v`, hits, added, err)
+	}
+
+	if hits, added, err := store.IncrUnder("other", 11, 10, now, time.Minute); err != nil || added || hits != 0 {
+		t.Fatalf(`Expected more hits than the limit to never be added.. Got %d, %v, %v`, hits, added, err)
+	}
+}
+
+func TestStore_IncrUnder_concurrent(t *testing.T) {
+	store, _ := setUp(t, time.Minute, Clock(gottletest.NewClock(time.Now())))
+
+	var (
+		wg    sync.WaitGroup
+		added atomic.Int32
+	)
+
+	for i := 0; i < 40; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			if _, ok, err := store.IncrUnder("key", 1, 25, time.Now(), time.Minute); err == nil && ok {
+				added.Add(1)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	//Callers whose hits fit are never denied because of the others
+	if added.Load() != 25 {
+		t.Fatalf(`Expected %d hits to be added.. Got %d`, 25, added.Load())
+	}
+
+	if hits, _, err := store.Count("key"); err != nil || hits != 25 {
+		t.Fatalf(`Hits differ.. Expected %d.. Got %d, %v`, 25, hits, err)
+	}
+}
+
+func TestStore_IncrUnder_afterDecr(t *testing.T) {
+	store, _ := setUp(t, time.Minute, Clock(gottletest.NewClock(time.Now())))
+
+	store.Incr("key", 10, time.Now(), time.Minute)
+
+	//memcached pads the value, "9 "
+	store.Decr("key", 1)
+
+	if hits, added, err := store.IncrUnder("key", 1, 10, time.Now(), time.Minute); err != nil || !added || hits != 10 {
+		t.Fatalf(`Expected the hit to be added.. Got %d, %v, %v`, hits, added, err)
+	}
+}
+
+func TestStore_Decr(t *testing.T) {
+	store, _ := setUp(t, time.Minute, Clock(gottletest.NewClock(time.Now())))
+
+	if hits, err := store.Decr("key", 1); err != nil || hits != 0 {
+		t.Fatalf(`Expected a missing key to be left alone.. Got %d, %v`, hits, err)
+	}
+
+	now := time.Now()
+
+	store.Incr("key", 5, now, time.Minute)
+
+	if hits, err := store.Decr("key", 2); err != nil || hits != 3 {
+		t.Fatalf(`Expected %d hits to be left.. Got %d, %v`, 3, hits, err)
+	}
+
+	if hits, err := store.Decr("key", 5); err != nil || hits != 0 {
+		t.Fatalf(`Expected the hits not to go below zero.. Got %d, %v`, hits, err)
+	}
+
+	if hits, _, err := store.Count("key"); err != nil || hits != 0 {
+		t.Fatalf(`Expected the key to be kept with no hits.. Got %d, %v`, hits, err)
+	}
+}
+
+func TestStore_withThrottler(t *testing.T) {
+	//A stopped clock keeps the test from straddling two windows
+	store, _ := setUp(t, time.Minute, Clock(gottletest.NewClock(time.Now())))
+
+	r := httptest.NewRequest(http.MethodGet, "/oops", nil)
+	r.Header.Set("X-Forwarded-For", "123.456.789.000")
+
+	throttler := gottle.NewOneCacheThrottler(
+		gottle.Store(store),
+		gottle.ThrottleCondition(time.Minute, 5))
+
+	gottletest.AssertLimitedAfter(t, throttler, r, 5)
+
+	if err := throttler.Clear(r); err != nil {
+		t.Fatalf(`An error occurred while clearing the request.. %v`, err)
+	}
+
+	gottletest.AssertNotLimited(t, throttler, r)
+}
+
+func TestStore_storedOverride(t *testing.T) {
+	store, _ := setUp(t, time.Minute)
+
+	throttler := gottle.NewOneCacheThrottler(
+		gottle.Store(store),
+		gottle.StoredOverrides(true),
+		gottle.OverrideCacheTTL(0),
+		gottle.ThrottleCondition(time.Minute, 5))
+
+	if err := throttler.SetOverride("123.456.789.000", 50, time.Minute); err != nil {
+		t.Fatalf(`An error occurred while setting the override.. %v`, err)
+	}
+
+	status, err := throttler.Status("123.456.789.000")
+
+	if err != nil {
+		t.Fatalf(`An error occurred while fetching the status.. %v`, err)
+	}
+
+	if status.Limit != 50 {
+		t.Fatalf(`Limits differ.. Expected the override to have been kept with %d.. Got %d`, 50, status.Limit)
+	}
+}
+
+func TestStore_quota(t *testing.T) {
+	store, _ := setUp(t, time.Minute)
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal(`Expected quotas to be rejected with the memcached store`)
+		}
+	}()
+
+	gottle.NewOneCacheThrottler(gottle.Store(store), gottle.Quota(gottle.Month, nil))
+}
@@ -0,0 +1,150 @@
+package gottle
+
+import (
+	"context"
+	"time"
+
+	"github.com/adelowo/onecache"
+)
+
+const defaultName = "default"
+
+//Operations on the store reported to a MetricsRecorder
+const (
+	OpHas       = "has"
+	OpGet       = "get"
+	OpSet       = "set"
+	OpDelete    = "delete"
+	OpIncr      = "incr"
+	OpIncrUnder = "incr_under"
+	OpCount     = "count"
+	OpDecr      = "decr"
+)
+
+//MetricsRecorder receives measurements from the throttler.
+//name is the name of the throttler, as set by the Name option,
+//so measurements can be told apart without labelling them by key
+type MetricsRecorder interface {
+	//Decision records the outcome of a call to Throttle.
+	//shadow is true for throttlers that only record what they would have
+	//decided, see the Shadow option, so allowed is the would-be outcome
+	Decision(name string, allowed, shadow bool)
+
+	//StoreError records a failed operation on the store
+	StoreError(name, op string)
+
+	//StoreLatency records how long an operation on the store took
+	StoreLatency(name, op string, d time.Duration)
+}
+
+//LimitRecorder is implemented by metrics recorders that also keep track
+//of the limit of each throttler, which can change while it is in use
+//through SetThrottleCondition or an Adaptive limiter
+type LimitRecorder interface {
+	Limit(name string, limit int)
+}
+
+//Tracer traces the work the throttler does on behalf of a request.
+//ctx is the context of the request being throttled
+type Tracer interface {
+	//StoreOp starts tracing an operation on the store and returns a function
+	//that ends it. The function is passed the error the operation failed with, if any
+	StoreOp(ctx context.Context, name, op string) func(err error)
+
+	//Decision records the outcome of a call to Throttle and the
+	//attempts the client has left. shadow is true for throttlers
+	//in shadow mode, whose decisions are never enforced
+	Decision(ctx context.Context, name string, allowed, shadow bool, remaining int)
+}
+
+//reportLimit reports the current limit to the metrics recorder,
+//if it keeps track of it
+func (t *OnecacheThrottler) reportLimit() {
+	if recorder, ok := t.metrics.(LimitRecorder); ok {
+		limit, _ := t.throttleCondition()
+		recorder.Limit(t.name, limit)
+	}
+}
+
+//timed runs fn, an operation on the store carried out for c, and reports
+//it to the tracer, metrics recorder and hooks. Cache misses are an expected
+//outcome rather than failures
+func (t *OnecacheThrottler) timed(c client, op string, fn func() error) error {
+	var end func(error)
+
+	if t.tracer != nil {
+		end = t.tracer.StoreOp(c.ctx, t.name, op)
+	}
+
+	start := time.Now()
+
+	err := fn()
+
+	if end != nil {
+		if err == onecache.ErrCacheMiss {
+			end(nil)
+		} else {
+			end(err)
+		}
+	}
+
+	if t.metrics != nil {
+		t.metrics.StoreLatency(t.name, op, time.Since(start))
+	}
+
+	if err != nil && err != onecache.ErrCacheMiss {
+		if t.metrics != nil {
+			t.metrics.StoreError(t.name, op)
+		}
+
+		t.logStoreError(c, op, err)
+
+		t.registry().storeError(StoreErrorEvent{Event: t.event(c, 0), Op: op, Err: err})
+	}
+
+	return err
+}
+
+//decided reports the outcome of a call to Throttle that added n hits for c,
+//leaving it with hits
+func (t *OnecacheThrottler) decided(c client, hits, n int, err error) {
+	switch err {
+	case nil:
+		if t.metrics != nil {
+			t.metrics.Decision(t.name, true, t.shadow)
+		}
+
+		if t.tracer != nil {
+			t.tracer.Decision(c.ctx, t.name, true, t.shadow, remaining(c, hits))
+		}
+
+		t.registry().allowed(t.event(c, hits))
+
+		//Only the hits that pushed the client to it's limit count as
+		//the transition, every request after that is simply denied
+		if hits >= c.limit && hits-n < c.limit {
+			t.logLimited(c, hits)
+			t.registry().limited(t.event(c, hits))
+		}
+
+	case ErrClientIsRateLimited:
+		if t.metrics != nil {
+			t.metrics.Decision(t.name, false, t.shadow)
+		}
+
+		if t.tracer != nil {
+			t.tracer.Decision(c.ctx, t.name, false, t.shadow, remaining(c, hits))
+		}
+
+		t.logDenied(c, hits)
+	}
+}
+
+//remaining returns the attempts c has left with hits
+func remaining(c client, hits int) int {
+	if hits >= c.limit {
+		return 0
+	}
+
+	return c.limit - hits
+}
@@ -0,0 +1,207 @@
+package gottle
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/adelowo/onecache/memory"
+)
+
+type recordedDecision struct {
+	name    string
+	allowed bool
+	shadow  bool
+}
+
+type fakeRecorder struct {
+	mu        sync.Mutex
+	decisions []recordedDecision
+	errors    map[string]int
+	latencies map[string]int
+}
+
+func newFakeRecorder() *fakeRecorder {
+	return &fakeRecorder{
+		errors:    make(map[string]int),
+		latencies: make(map[string]int),
+	}
+}
+
+func (f *fakeRecorder) Decision(name string, allowed, shadow bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.decisions = append(f.decisions, recordedDecision{name, allowed, shadow})
+}
+
+func (f *fakeRecorder) StoreError(name, op string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.errors[op]++
+}
+
+func (f *fakeRecorder) StoreLatency(name, op string, d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.latencies[op]++
+}
+
+//failingStore is an in memory store whose Set always fails
+type failingStore struct {
+	*memory.InMemoryStore
+	err error
+}
+
+func (f *failingStore) Set(key string, data []byte, expires time.Duration) error {
+	return f.err
+}
+
+func TestOnecacheThrottler_metrics(t *testing.T) {
+	r, teardown, err := setUp(t)
+	defer teardown()
+
+	if err != nil {
+		t.Fatalf("An error occurred while setting up the test ..%v", err)
+	}
+
+	r.Header.Set(xForwardedFor, "123.456.789.000")
+
+	recorder := newFakeRecorder()
+
+	throttler := NewOneCacheThrottler(
+		Name("login"),
+		Metrics(recorder),
+		ThrottleCondition(time.Minute, 2))
+
+	for i := 0; i < 3; i++ {
+		throttler.Throttle(r)
+	}
+
+	expected := []recordedDecision{
+		{"login", true, false}, {"login", true, false}, {"login", false, false},
+	}
+
+	if len(recorder.decisions) != len(expected) {
+		t.Fatalf(`Decisions differ.. Expected %v.. Got %v`, expected, recorder.decisions)
+	}
+
+	for i, v := range expected {
+		if recorder.decisions[i] != v {
+			t.Fatalf(`Decisions differ.. Expected %v.. Got %v`, expected, recorder.decisions)
+		}
+	}
+
+	if recorder.latencies[OpSet] != 2 {
+		t.Fatalf(`Expected the latency of %d sets to be recorded.. Got %d`,
+			2, recorder.latencies[OpSet])
+	}
+}
+
+func TestOnecacheThrottler_metrics_storeError(t *testing.T) {
+	r, teardown, err := setUp(t)
+	defer teardown()
+
+	if err != nil {
+		t.Fatalf("An error occurred while setting up the test ..%v", err)
+	}
+
+	r.Header.Set(xForwardedFor, "123.456.789.000")
+
+	recorder := newFakeRecorder()
+
+	expectedErr := errors.New("oops")
+
+	throttler := NewOneCacheThrottler(
+		Metrics(recorder),
+		Store(&failingStore{memory.New(), expectedErr}))
+
+	if err := throttler.Throttle(r); err != expectedErr {
+		t.Fatalf(`Errors differ.. Expected %v.. Got %v`, expectedErr, err)
+	}
+
+	if recorder.errors[OpSet] != 1 {
+		t.Fatalf(`Expected the failed set to be recorded.. Got %v`, recorder.errors)
+	}
+
+	//A failure is neither an allowed nor a denied decision
+	if len(recorder.decisions) != 0 {
+		t.Fatalf(`Expected no decision to be recorded.. Got %v`, recorder.decisions)
+	}
+}
+
+type tracedDecision struct {
+	allowed   bool
+	remaining int
+}
+
+type fakeTracer struct {
+	ops       []string
+	errs      []error
+	decisions []tracedDecision
+	ctxs      []context.Context
+}
+
+func (f *fakeTracer) StoreOp(ctx context.Context, name, op string) func(error) {
+	f.ops = append(f.ops, op)
+	f.ctxs = append(f.ctxs, ctx)
+
+	return func(err error) {
+		f.errs = append(f.errs, err)
+	}
+}
+
+func (f *fakeTracer) Decision(ctx context.Context, name string, allowed, shadow bool, remaining int) {
+	f.decisions = append(f.decisions, tracedDecision{allowed, remaining})
+}
+
+type ctxKey struct{}
+
+func TestOnecacheThrottler_tracing(t *testing.T) {
+	r, teardown, err := setUp(t)
+	defer teardown()
+
+	if err != nil {
+		t.Fatalf("An error occurred while setting up the test ..%v", err)
+	}
+
+	r.Header.Set(xForwardedFor, "123.456.789.000")
+	r = r.WithContext(context.WithValue(r.Context(), ctxKey{}, "request"))
+
+	tracer := new(fakeTracer)
+
+	throttler := NewOneCacheThrottler(
+		Tracing(tracer),
+		ThrottleCondition(time.Minute, 2))
+
+	for i := 0; i < 3; i++ {
+		throttler.Throttle(r)
+	}
+
+	expected := []tracedDecision{{true, 1}, {true, 0}, {false, 0}}
+
+	if len(tracer.decisions) != len(expected) {
+		t.Fatalf(`Decisions differ.. Expected %v.. Got %v`, expected, tracer.decisions)
+	}
+
+	for i, v := range expected {
+		if tracer.decisions[i] != v {
+			t.Fatalf(`Decisions differ.. Expected %v.. Got %v`, expected, tracer.decisions)
+		}
+	}
+
+	if len(tracer.ops) == 0 || len(tracer.ops) != len(tracer.errs) {
+		t.Fatalf(`Every store operation is supposed to be started and ended.. Got %v and %v`,
+			tracer.ops, tracer.errs)
+	}
+
+	for _, ctx := range tracer.ctxs {
+		if ctx.Value(ctxKey{}) != "request" {
+			t.Fatal(`Store operations are supposed to be traced in the request's context`)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package gottle
+
+import (
+	"errors"
+	"net/http"
+)
+
+//Middleware returns a function that throttles every request before it
+//reaches the wrapped handler. Rate limited clients get a 429 Too Many Requests
+//and shed requests a 503 Service Unavailable. Requests that can't be throttled
+//because of a store error are let through, the error having been reported
+//to the throttler's hooks, metrics and logs
+func Middleware(t Throttler) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if rejected(w, t.Throttle(r)) {
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+//CostFunc returns the number of hits a request costs,
+//based on it's route, query or body for example
+type CostFunc func(r *http.Request) int
+
+//WeightedMiddleware works like Middleware for requests that cost more
+//than one hit, as returned by cost. Costs below one are taken as one,
+//so requests are never let through without being counted
+func WeightedMiddleware(t WeightedThrottler, cost CostFunc) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if rejected(w, t.ThrottleN(r, max(cost(r), 1))) {
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+//rejected writes the response for requests err says can't go through
+func rejected(w http.ResponseWriter, err error) bool {
+	switch {
+	case errors.Is(err, ErrClientIsRateLimited):
+		http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+	case errors.Is(err, ErrRequestShed):
+		http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+	default:
+		return false
+	}
+
+	return true
+}
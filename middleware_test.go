@@ -0,0 +1,107 @@
+package gottle
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/adelowo/onecache/memory"
+)
+
+func TestMiddleware(t *testing.T) {
+	throttler := NewOneCacheThrottler(ThrottleCondition(time.Minute, 2))
+
+	h := Middleware(throttler)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	expected := []int{http.StatusNoContent, http.StatusNoContent, http.StatusTooManyRequests}
+
+	for i, code := range expected {
+		r := httptest.NewRequest(http.MethodGet, "/oops", nil)
+		r.Header.Set(xForwardedFor, "123.456.789.000")
+
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+
+		if w.Code != code {
+			t.Fatalf(`Status codes differ for request %d.. Expected %d.. Got %d`, i+1, code, w.Code)
+		}
+	}
+}
+
+func TestMiddleware_storeError(t *testing.T) {
+	throttler := NewOneCacheThrottler(
+		Store(&failingStore{memory.New(), errors.New("oops")}))
+
+	h := Middleware(throttler)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/oops", nil))
+
+	if w.Code != http.StatusNoContent {
+		t.Fatalf(`Requests are supposed to be let through on store errors.. Got %d`, w.Code)
+	}
+}
+
+func TestWeightedMiddleware(t *testing.T) {
+	throttler := NewOneCacheThrottler(ThrottleCondition(time.Minute, 10))
+
+	cost := func(r *http.Request) int {
+		switch r.URL.Path {
+		case "/export":
+			return 8
+		case "/free":
+			return 0
+		}
+
+		return 1
+	}
+
+	h := WeightedMiddleware(throttler, cost)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	tt := []struct {
+		path string
+		code int
+	}{
+		{"/export", http.StatusNoContent},
+		{"/export", http.StatusTooManyRequests},
+		//Costs below one still count as one
+		{"/free", http.StatusNoContent},
+		{"/ping", http.StatusNoContent},
+		{"/free", http.StatusTooManyRequests},
+	}
+
+	for i, v := range tt {
+		r := httptest.NewRequest(http.MethodGet, v.path, nil)
+		r.Header.Set(xForwardedFor, "123.456.789.000")
+
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+
+		if w.Code != v.code {
+			t.Fatalf(`Status codes differ for request %d.. Expected %d.. Got %d`, i+1, v.code, w.Code)
+		}
+	}
+}
+
+func TestMiddleware_wrappedErrors(t *testing.T) {
+	cases := map[error]int{
+		errors.Join(ErrClientIsRateLimited, errors.New("oops")): http.StatusTooManyRequests,
+		errors.Join(ErrRequestShed, errors.New("oops")):         http.StatusServiceUnavailable,
+	}
+
+	for err, expected := range cases {
+		w := httptest.NewRecorder()
+
+		if !rejected(w, err) || w.Code != expected {
+			t.Fatalf(`Status codes differ.. Expected %d.. Got %d`, expected, w.Code)
+		}
+	}
+}
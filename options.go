@@ -1,6 +1,7 @@
 package gottle
 
 import (
+	"log/slog"
 	"time"
 
 	"github.com/adelowo/onecache"
@@ -42,3 +43,132 @@ func ThrottleCondition(interval time.Duration, maxRequests int) Option {
 		t.maxRequests = maxRequests
 	}
 }
+
+//StoredOverrides is a configuration Option that keeps per key overrides
+//in the store, where they are set with SetOverride and shared by every
+//throttler using the store. Stored overrides take precedence over
+//the ones found by OverrideLookup
+func StoredOverrides(enabled bool) Option {
+	return func(t *OnecacheThrottler) {
+		t.overriding().stored = enabled
+	}
+}
+
+//OverrideLookup is a configuration Option that sets a function
+//that supplies per key overrides, such as from a customer database
+func OverrideLookup(fn OverrideFunc) Option {
+	return func(t *OnecacheThrottler) {
+		t.overriding().lookup = fn
+	}
+}
+
+//OverrideCacheTTL is a configuration Option that sets how long the override
+//found for a key, or the lack of one, is cached before it is looked up again.
+//It defaults to a minute, a zero or negative ttl disables caching
+func OverrideCacheTTL(ttl time.Duration) Option {
+	return func(t *OnecacheThrottler) {
+		t.overriding().ttl = ttl
+	}
+}
+
+//Shadow is a configuration Option that turns shadow mode on or off.
+//In shadow mode, the throttler keeps counting and reports it's decisions to
+//hooks, metrics, tracing and logs, flagged as shadow, but Throttle never
+//returns ErrClientIsRateLimited. That allows tighter limits to be tried
+//on real traffic before they are enforced
+func Shadow(enabled bool) Option {
+	return func(t *OnecacheThrottler) {
+		t.shadow = enabled
+	}
+}
+
+//Candidate is a configuration Option that runs a second policy in shadow
+//mode next to the throttler, so their decisions can be compared.
+//See the docs of newCandidate for what the candidate inherits
+func Candidate(opts ...Option) Option {
+	return func(t *OnecacheThrottler) {
+		t.candidateOpts = append([]Option{}, opts...)
+	}
+}
+
+//Quota is a configuration Option that turns quota mode on. Hits are then
+//counted in calendar windows of period, starting at midnight in loc or UTC
+//if it is nil, and every hit of a window counts until it ends, such as
+//"100000 requests per calendar month, resetting on the 1st". The limit is
+//still set by ThrottleCondition and overrides, their interval is ignored.
+//Stores that count in windows of their own, like memcached, don't support it
+//and NewOneCacheThrottler panics if it is given one along with a quota
+func Quota(period Period, loc *time.Location) Option {
+	return func(t *OnecacheThrottler) {
+		if loc == nil {
+			loc = time.UTC
+		}
+
+		t.quota = &quota{period: period, location: loc}
+	}
+}
+
+//Clock is a configuration Option that sets the source of time used
+//when deciding if a client is still within it's interval
+func Clock(clock TimeProvider) Option {
+	return func(t *OnecacheThrottler) {
+		t.clock = clock
+	}
+}
+
+//Name is a configuration Option that names the throttler.
+//The name is what measurements are labelled with, so it should
+//identify the rule or limiter rather than a client
+func Name(name string) Option {
+	return func(t *OnecacheThrottler) {
+		t.name = name
+	}
+}
+
+//Metrics is a configuration Option that sets where measurements
+//of throttling decisions and store operations are sent
+func Metrics(recorder MetricsRecorder) Option {
+	return func(t *OnecacheThrottler) {
+		t.metrics = recorder
+	}
+}
+
+//Tracing is a configuration Option that sets the tracer operations
+//on the store and throttling decisions are reported to
+func Tracing(tracer Tracer) Option {
+	return func(t *OnecacheThrottler) {
+		t.tracer = tracer
+	}
+}
+
+//Logger is a configuration Option that sets where store errors, items that
+//can't be decoded, lockouts and denied requests are logged.
+//Levels can be changed with LoggingLevels and denied requests are sampled,
+//see LogDeniedSampling. Messages about clients throttled by key rather than
+//by request, such as with ThrottleKey, are logged with an empty ip
+func Logger(l *slog.Logger) Option {
+	return func(t *OnecacheThrottler) {
+		t.logging().l = l
+	}
+}
+
+//LoggingLevels is a configuration Option that sets the level each kind of
+//message is logged at. Levels that are left nil keep the ones already set.
+//It has no effect unless the Logger option is set
+func LoggingLevels(levels LogLevels) Option {
+	return func(t *OnecacheThrottler) {
+		t.logging().levels = t.logging().levels.merge(levels)
+	}
+}
+
+//LogDeniedSampling is a configuration Option that logs only one in every n
+//denied requests. It defaults to 100, a value of 1 or less logs every one of them
+func LogDeniedSampling(n int) Option {
+	return func(t *OnecacheThrottler) {
+		if n < 1 {
+			n = 1
+		}
+
+		t.logging().sampling = uint64(n)
+	}
+}
@@ -1,6 +1,7 @@
 package gottle
 
 import (
+	"log/slog"
 	"reflect"
 	"testing"
 	"time"
@@ -71,3 +72,73 @@ func TestThrottleCondition(t *testing.T) {
       Expected %d.. Got %d`, maxRequests, throttler.maxRequests)
 	}
 }
+
+type fixedClock struct {
+	t time.Time
+}
+
+func (f fixedClock) Now() time.Time {
+	return f.t
+}
+
+func TestClock(t *testing.T) {
+	clock := fixedClock{time.Date(2017, time.March, 1, 0, 0, 0, 0, time.UTC)}
+
+	throttler := NewOneCacheThrottler(Clock(clock))
+
+	if !reflect.DeepEqual(clock, throttler.clock) {
+		t.Fatalf(`
+      Clock differs... Expected %v \n Got %v`, clock, throttler.clock)
+	}
+
+	if actual := throttler.now(); !actual.Equal(clock.t) {
+		t.Fatalf(`
+      Time differs... Expected %v \n Got %v`, clock.t, actual)
+	}
+}
+
+func TestName(t *testing.T) {
+	if throttler := NewOneCacheThrottler(); throttler.name != defaultName {
+		t.Fatalf(`
+      Name differs... Expected %v \n Got %v`, defaultName, throttler.name)
+	}
+
+	throttler := NewOneCacheThrottler(Name("login"))
+
+	if throttler.name != "login" {
+		t.Fatalf(`
+      Name differs... Expected %v \n Got %v`, "login", throttler.name)
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	recorder := newFakeRecorder()
+
+	throttler := NewOneCacheThrottler(Metrics(recorder))
+
+	if !reflect.DeepEqual(recorder, throttler.metrics) {
+		t.Fatalf(`
+      Metrics recorder differs... Expected %v \n Got %v`, recorder, throttler.metrics)
+	}
+}
+
+func TestLogger(t *testing.T) {
+	l := slog.Default()
+
+	throttler := NewOneCacheThrottler(LogDeniedSampling(0), Logger(l))
+
+	if throttler.logger.l != l {
+		t.Fatalf(`
+      Logger differs... Expected %v \n Got %v`, l, throttler.logger.l)
+	}
+
+	if throttler.logger.sampling != 1 {
+		t.Fatalf(`
+      Sampling differs... Expected %d \n Got %d`, 1, throttler.logger.sampling)
+	}
+
+	if !reflect.DeepEqual(defaultLogLevels, throttler.logger.levels) {
+		t.Fatalf(`
+      Levels differ... Expected %v \n Got %v`, defaultLogLevels, throttler.logger.levels)
+	}
+}
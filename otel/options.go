@@ -0,0 +1,13 @@
+package otel
+
+import "go.opentelemetry.io/otel/trace"
+
+//Option configures a Tracer
+type Option func(*Tracer)
+
+//TracerProvider is an Option that sets the provider spans are created from
+func TracerProvider(provider trace.TracerProvider) Option {
+	return func(t *Tracer) {
+		t.provider = provider
+	}
+}
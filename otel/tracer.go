@@ -0,0 +1,91 @@
+//Package otel traces gottle with OpenTelemetry.
+//
+//Every operation on the store gets it's own span, a child of the span
+//active in the request's context, and the outcome of every call to
+//Throttle is recorded as attributes on that active span.
+//Decisions made in shadow mode are recorded as span events instead,
+//so they don't overwrite the decision that was enforced
+package otel
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/adelowo/gottle/otel"
+
+//Attribute keys set on spans
+const (
+	LimiterKey   = attribute.Key("gottle.limiter")
+	OpKey        = attribute.Key("gottle.store.op")
+	DecisionKey  = attribute.Key("gottle.decision")
+	RemainingKey = attribute.Key("gottle.remaining")
+)
+
+//ShadowDecisionEvent is the name of the span event shadow decisions are recorded as
+const ShadowDecisionEvent = "gottle.shadow_decision"
+
+//Tracer implements gottle.Tracer on top of an OpenTelemetry TracerProvider.
+//Pass it to the throttler with the gottle.Tracing option
+type Tracer struct {
+	provider trace.TracerProvider
+	tracer   trace.Tracer
+}
+
+//New returns a Tracer.
+//It uses the global TracerProvider unless one is set with the TracerProvider option
+func New(opts ...Option) *Tracer {
+	t := &Tracer{provider: otel.GetTracerProvider()}
+
+	for _, opt := range opts {
+		opt(t)
+	}
+
+	t.tracer = t.provider.Tracer(instrumentationName)
+
+	return t
+}
+
+//StoreOp starts a span for an operation on the store
+func (t *Tracer) StoreOp(ctx context.Context, name, op string) func(error) {
+	_, span := t.tracer.Start(ctx, "gottle.store."+op,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(LimiterKey.String(name), OpKey.String(op)))
+
+	return func(err error) {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+
+		span.End()
+	}
+}
+
+//Decision records the outcome of a call to Throttle on the span active in ctx
+func (t *Tracer) Decision(ctx context.Context, name string, allowed, shadow bool, remaining int) {
+	decision := "denied"
+
+	if allowed {
+		decision = "allowed"
+	}
+
+	attrs := []attribute.KeyValue{
+		LimiterKey.String(name),
+		DecisionKey.String(decision),
+		RemainingKey.Int(remaining),
+	}
+
+	span := trace.SpanFromContext(ctx)
+
+	if shadow {
+		span.AddEvent(ShadowDecisionEvent, trace.WithAttributes(attrs...))
+		return
+	}
+
+	span.SetAttributes(attrs...)
+}
@@ -0,0 +1,161 @@
+package otel
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/adelowo/gottle"
+	"github.com/adelowo/gottle/gottletest"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+var _ gottle.Tracer = &Tracer{}
+
+func setUp(t *testing.T) (*tracetest.InMemoryExporter, *sdktrace.TracerProvider) {
+	exporter := tracetest.NewInMemoryExporter()
+
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	t.Cleanup(func() { provider.Shutdown(context.Background()) })
+
+	return exporter, provider
+}
+
+func attributes(span tracetest.SpanStub) map[attribute.Key]attribute.Value {
+	attrs := make(map[attribute.Key]attribute.Value)
+
+	for _, kv := range span.Attributes {
+		attrs[kv.Key] = kv.Value
+	}
+
+	return attrs
+}
+
+func TestTracer(t *testing.T) {
+	exporter, provider := setUp(t)
+
+	store := gottletest.NewStore(nil)
+
+	throttler := gottle.NewOneCacheThrottler(
+		gottle.Name("login"),
+		gottle.Store(store),
+		gottle.Tracing(New(TracerProvider(provider))),
+		gottle.ThrottleCondition(time.Minute, 5))
+
+	ctx, parent := provider.Tracer("test").Start(context.Background(), "request")
+
+	r := httptest.NewRequest(http.MethodGet, "/oops", nil).WithContext(ctx)
+	r.Header.Set("X-Forwarded-For", "123.456.789.000")
+
+	if err := throttler.Throttle(r); err != nil {
+		t.Fatalf(`An error occurred while throttling the request.. %v`, err)
+	}
+
+	parent.End()
+
+	spans := exporter.GetSpans()
+
+	var request tracetest.SpanStub
+	var storeSpans int
+
+	for _, span := range spans {
+		if span.Name == "request" {
+			request = span
+			continue
+		}
+
+		storeSpans++
+
+		if span.Parent.SpanID() != parent.SpanContext().SpanID() {
+			t.Fatalf(`Store span %s is supposed to be a child of the request's span`, span.Name)
+		}
+
+		if attrs := attributes(span); attrs[LimiterKey].AsString() != "login" {
+			t.Fatalf(`Store span %s is missing the limiter.. Got %v`, span.Name, attrs)
+		}
+	}
+
+	if storeSpans != store.Calls(gottletest.OpHas)+store.Calls(gottletest.OpGet)+store.Calls(gottletest.OpSet) {
+		t.Fatalf(`Expected a span per store operation.. Got %d`, storeSpans)
+	}
+
+	attrs := attributes(request)
+
+	if attrs[DecisionKey].AsString() != "allowed" || attrs[RemainingKey].AsInt64() != 4 {
+		t.Fatalf(`The decision was not recorded on the request's span.. Got %v`, attrs)
+	}
+}
+
+func TestTracer_storeError(t *testing.T) {
+	exporter, provider := setUp(t)
+
+	store := gottletest.NewStore(nil)
+	store.Fail(gottletest.OpSet, errors.New("oops"))
+
+	throttler := gottle.NewOneCacheThrottler(
+		gottle.Store(store),
+		gottle.Tracing(New(TracerProvider(provider))))
+
+	r := httptest.NewRequest(http.MethodGet, "/oops", nil)
+	r.Header.Set("X-Forwarded-For", "123.456.789.000")
+
+	throttler.Throttle(r)
+
+	for _, span := range exporter.GetSpans() {
+		if span.Name != "gottle.store.set" {
+			continue
+		}
+
+		if span.Status.Code != codes.Error {
+			t.Fatalf(`The failed operation's span is supposed to be marked as an error.. Got %v`,
+				span.Status)
+		}
+
+		return
+	}
+
+	t.Fatal(`Expected a span for the failed set`)
+}
+
+func TestTracer_shadow(t *testing.T) {
+	exporter, provider := setUp(t)
+
+	throttler := gottle.NewOneCacheThrottler(
+		gottle.Name("login"),
+		gottle.Tracing(New(TracerProvider(provider))),
+		gottle.ThrottleCondition(time.Minute, 5),
+		gottle.Candidate(gottle.ThrottleCondition(time.Minute, 1)))
+
+	ctx, parent := provider.Tracer("test").Start(context.Background(), "request")
+
+	r := httptest.NewRequest(http.MethodGet, "/oops", nil).WithContext(ctx)
+	r.Header.Set("X-Forwarded-For", "123.456.789.000")
+
+	throttler.Throttle(r)
+
+	parent.End()
+
+	for _, span := range exporter.GetSpans() {
+		if span.Name != "request" {
+			continue
+		}
+
+		if attrs := attributes(span); attrs[LimiterKey].AsString() != "login" {
+			t.Fatalf(`The shadow decision is not supposed to overwrite the enforced one.. Got %v`, attrs)
+		}
+
+		if len(span.Events) != 1 || span.Events[0].Name != ShadowDecisionEvent {
+			t.Fatalf(`Expected the shadow decision to be recorded as an event.. Got %v`, span.Events)
+		}
+
+		return
+	}
+
+	t.Fatal(`Expected the request's span to have been exported`)
+}
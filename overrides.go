@@ -0,0 +1,289 @@
+package gottle
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/adelowo/onecache"
+)
+
+const (
+	overrideKeyPrefix       = "override:"
+	defaultOverrideCacheTTL = time.Minute
+	maxCachedOverrides      = 10000
+
+	//storedOverrideExpiry keeps stored overrides around for good,
+	//as not every store treats a zero expiry as never expiring
+	storedOverrideExpiry = time.Hour * 24 * 365 * 10
+)
+
+//OpOverride is the lookup of a key's override through an OverrideFunc.
+//It is reported to a MetricsRecorder like the operations on the store
+const OpOverride = "override"
+
+//ErrOverridesNotStored is returned when setting or removing an override
+//on a throttler that does not keep them in it's store
+var ErrOverridesNotStored = errors.New(
+	`gottle: Overrides are not kept in the store, see the StoredOverrides option`)
+
+//ErrInvalidOverride is returned when setting an override whose limit
+//is not greater than zero or whose interval is negative
+var ErrInvalidOverride = errors.New(
+	`gottle: An override needs a limit greater than zero and an interval that is not negative`)
+
+//Override is a limit that applies to a single key instead of
+//the one set by ThrottleCondition
+type Override struct {
+	Limit int
+
+	//Interval defaults to the throttler's if it is zero
+	Interval time.Duration
+}
+
+//OverrideFunc looks up the override for key.
+//ok is false if key has none
+type OverrideFunc func(key string) (o Override, ok bool, err error)
+
+//overrides holds where a throttler finds overrides and
+//the ones it has already found
+type overrides struct {
+	stored bool
+	lookup OverrideFunc
+	ttl    time.Duration
+
+	mu    sync.Mutex
+	cache map[string]cachedOverride
+}
+
+type cachedOverride struct {
+	o         Override
+	ok        bool
+	expiresAt time.Time
+}
+
+//overriding returns the throttler's overrides, creating them with the defaults if needed
+func (t *OnecacheThrottler) overriding() *overrides {
+	if t.overrides == nil {
+		t.overrides = &overrides{
+			ttl:   defaultOverrideCacheTTL,
+			cache: make(map[string]cachedOverride),
+		}
+	}
+
+	return t.overrides
+}
+
+func (o *overrides) cached(key string, now time.Time) (cachedOverride, bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	cached, found := o.cache[key]
+
+	if !found || now.After(cached.expiresAt) {
+		return cachedOverride{}, false
+	}
+
+	return cached, true
+}
+
+func (o *overrides) remember(key string, override Override, ok bool, now time.Time) {
+	if o.ttl <= 0 {
+		return
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if len(o.cache) >= maxCachedOverrides {
+		for k, v := range o.cache {
+			if now.After(v.expiresAt) {
+				delete(o.cache, k)
+			}
+		}
+
+		//Every entry is still fresh, start over rather than grow without bounds
+		if len(o.cache) >= maxCachedOverrides {
+			o.cache = make(map[string]cachedOverride)
+		}
+	}
+
+	o.cache[key] = cachedOverride{o: override, ok: ok, expiresAt: now.Add(o.ttl)}
+}
+
+func (o *overrides) forget(key string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	delete(o.cache, key)
+}
+
+//limitsFor returns the limit and interval that apply to c.
+//An override stored for c takes precedence over the one found by the
+//OverrideFunc, which takes precedence over ThrottleCondition.
+//Overrides that can't be found because of an error are ignored
+func (t *OnecacheThrottler) limitsFor(c client) (int, time.Duration) {
+	maxRequests, interval := t.throttleCondition()
+
+	o, ok := t.override(c)
+
+	if !ok {
+		return maxRequests, interval
+	}
+
+	if o.Interval == 0 {
+		return o.Limit, interval
+	}
+
+	return o.Limit, o.Interval
+}
+
+func (t *OnecacheThrottler) override(c client) (Override, bool) {
+	ov := t.overrides
+
+	if ov == nil || (!ov.stored && ov.lookup == nil) {
+		return Override{}, false
+	}
+
+	now := t.now()
+
+	if cached, found := ov.cached(c.key, now); found {
+		return cached.o, cached.ok
+	}
+
+	o, ok, err := t.findOverride(c)
+
+	//Not cached, so it is looked up again on the next request
+	if err != nil {
+		return Override{}, false
+	}
+
+	if ok && o.Limit < 1 {
+		ok = false
+	}
+
+	ov.remember(c.key, o, ok, now)
+
+	return o, ok
+}
+
+func (t *OnecacheThrottler) findOverride(c client) (Override, bool, error) {
+	if t.overrides.stored {
+		o, ok, err := t.loadOverride(c)
+
+		if err != nil || ok {
+			return o, ok, err
+		}
+	}
+
+	lookup := t.overrides.lookup
+
+	if lookup == nil {
+		return Override{}, false, nil
+	}
+
+	var o Override
+	var ok bool
+
+	err := t.timed(c, OpOverride, func() (err error) {
+		o, ok, err = lookup(c.key)
+		return err
+	})
+
+	return o, ok, err
+}
+
+//overrideClient returns a client for the override stored for c
+func overrideClient(c client) client {
+	return client{ctx: c.ctx, ip: c.ip, key: overrideKeyPrefix + c.key}
+}
+
+func (t *OnecacheThrottler) loadOverride(c client) (Override, bool, error) {
+	oc := overrideClient(c)
+
+	var buf []byte
+
+	err := t.timed(oc, OpGet, func() (err error) {
+		buf, err = t.store.Get(oc.key)
+		return err
+	})
+
+	if err == onecache.ErrCacheMiss {
+		return Override{}, false, nil
+	}
+
+	if err != nil {
+		return Override{}, false, err
+	}
+
+	var o Override
+
+	if err := gob.NewDecoder(bytes.NewBuffer(buf)).Decode(&o); err != nil {
+		t.logDecodeError(oc, err)
+		return Override{}, false, err
+	}
+
+	return o, true, nil
+}
+
+func (t *OnecacheThrottler) overridesStored() bool {
+	return t.overrides != nil && t.overrides.stored
+}
+
+//SetOverride stores an override for key, giving it limit requests per interval.
+//A zero interval keeps the throttler's. Other throttlers sharing the store
+//pick it up once their cached lookup for key expires, see OverrideCacheTTL
+func (t *OnecacheThrottler) SetOverride(key string, limit int, interval time.Duration) error {
+	if limit < 1 || interval < 0 {
+		return ErrInvalidOverride
+	}
+
+	if !t.overridesStored() {
+		return ErrOverridesNotStored
+	}
+
+	var buf bytes.Buffer
+
+	if err := gob.NewEncoder(&buf).Encode(Override{Limit: limit, Interval: interval}); err != nil {
+		return err
+	}
+
+	oc := overrideClient(client{ctx: context.Background(), key: key})
+
+	err := t.timed(oc, OpSet, func() error {
+		return t.store.Set(oc.key, buf.Bytes(), storedOverrideExpiry)
+	})
+
+	if err != nil {
+		return err
+	}
+
+	t.overrides.forget(key)
+
+	return nil
+}
+
+//RemoveOverride removes the override stored for key,
+//so it goes back to the throttler's limit
+func (t *OnecacheThrottler) RemoveOverride(key string) error {
+	if !t.overridesStored() {
+		return ErrOverridesNotStored
+	}
+
+	oc := overrideClient(client{ctx: context.Background(), key: key})
+
+	err := t.timed(oc, OpDelete, func() error {
+		return t.store.Delete(oc.key)
+	})
+
+	if err != nil && err != onecache.ErrCacheMiss {
+		return err
+	}
+
+	t.overrides.forget(key)
+
+	return nil
+}
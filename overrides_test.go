@@ -0,0 +1,157 @@
+package gottle
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestOnecacheThrottler_SetOverride(t *testing.T) {
+	r, teardown, err := setUp(t)
+	defer teardown()
+
+	if err != nil {
+		t.Fatalf("An error occurred while setting up the test ..%v", err)
+	}
+
+	r.Header.Set(xForwardedFor, "123.456.789.000")
+
+	throttler := NewOneCacheThrottler(
+		StoredOverrides(true),
+		ThrottleCondition(time.Minute, 1))
+
+	if err := throttler.SetOverride("123.456.789.000", 3, time.Hour); err != nil {
+		t.Fatalf(`An error occurred while setting the override.. %v`, err)
+	}
+
+	for i := 0; i < 3; i++ {
+		if err := throttler.Throttle(r); err != nil {
+			t.Fatalf(`Request %d is supposed to be allowed by the override.. Got %v`, i+1, err)
+		}
+	}
+
+	if err := throttler.Throttle(r); err != ErrClientIsRateLimited {
+		t.Fatalf(`Errors differ.. Expected %v.. Got %v`, ErrClientIsRateLimited, err)
+	}
+
+	status, _ := throttler.Status("123.456.789.000")
+
+	if status.Limit != 3 || status.ResetAt.Sub(time.Now()) < time.Minute*59 {
+		t.Fatalf(`The override was not applied to the status.. Got %+v`, status)
+	}
+
+	if err := throttler.RemoveOverride("123.456.789.000"); err != nil {
+		t.Fatalf(`An error occurred while removing the override.. %v`, err)
+	}
+
+	if status, _ := throttler.Status("123.456.789.000"); status.Limit != 1 {
+		t.Fatalf(`Limits differ.. Expected %d.. Got %d`, 1, status.Limit)
+	}
+}
+
+func TestOnecacheThrottler_SetOverride_errors(t *testing.T) {
+	throttler := NewOneCacheThrottler()
+
+	if err := throttler.SetOverride("key", 5, 0); err != ErrOverridesNotStored {
+		t.Fatalf(`Errors differ.. Expected %v.. Got %v`, ErrOverridesNotStored, err)
+	}
+
+	if err := throttler.RemoveOverride("key"); err != ErrOverridesNotStored {
+		t.Fatalf(`Errors differ.. Expected %v.. Got %v`, ErrOverridesNotStored, err)
+	}
+
+	throttler = NewOneCacheThrottler(StoredOverrides(true))
+
+	for _, limit := range []int{0, -1} {
+		if err := throttler.SetOverride("key", limit, 0); err != ErrInvalidOverride {
+			t.Fatalf(`Errors differ.. Expected %v.. Got %v`, ErrInvalidOverride, err)
+		}
+	}
+}
+
+func TestOnecacheThrottler_OverrideLookup(t *testing.T) {
+	clock := &fixedClock{time.Now()}
+
+	var lookups int
+
+	throttler := NewOneCacheThrottler(
+		Clock(clock),
+		StoredOverrides(true),
+		ThrottleCondition(time.Minute, 1),
+		OverrideCacheTTL(time.Minute),
+		OverrideLookup(func(key string) (Override, bool, error) {
+			lookups++
+
+			switch key {
+			case "enterprise":
+				return Override{Limit: 5000}, true, nil
+			case "broken":
+				return Override{}, false, errors.New("oops")
+			}
+
+			return Override{}, false, nil
+		}))
+
+	cases := []struct {
+		key      string
+		expected int
+	}{
+		{"enterprise", 5000},
+		{"free", 1},
+		{"broken", 1},
+	}
+
+	for _, v := range cases {
+		status, err := throttler.Status(v.key)
+
+		if err != nil || status.Limit != v.expected {
+			t.Fatalf(`Limits differ for %s.. Expected %d.. Got %d, %v`,
+				v.key, v.expected, status.Limit, err)
+		}
+	}
+
+	//Found overrides and their absence are cached, errors are not
+	throttler.Status("enterprise")
+	throttler.Status("free")
+	throttler.Status("broken")
+
+	if lookups != 4 {
+		t.Fatalf(`Lookups differ.. Expected %d.. Got %d`, 4, lookups)
+	}
+
+	//Stored overrides come first
+	if err := throttler.SetOverride("enterprise", 10, 0); err != nil {
+		t.Fatalf(`An error occurred while setting the override.. %v`, err)
+	}
+
+	if status, _ := throttler.Status("enterprise"); status.Limit != 10 {
+		t.Fatalf(`Limits differ.. Expected %d.. Got %d`, 10, status.Limit)
+	}
+
+	clock.t = clock.t.Add(time.Minute * 2)
+
+	throttler.Status("free")
+
+	if lookups != 5 {
+		t.Fatalf(`Expected the cached lookup to have expired.. Got %d lookups`, lookups)
+	}
+}
+
+func TestOnecacheThrottler_Reset_keepsOverrides(t *testing.T) {
+	throttler := NewOneCacheThrottler(
+		Store(newEnumeratingStore()),
+		StoredOverrides(true),
+		ThrottleCondition(time.Minute, 2))
+
+	throttleKeys(throttler, map[string]int{"10.0.0.1": 2})
+
+	throttler.SetOverride("10.0.0.1", 5, 0)
+
+	if n, err := throttler.Reset(""); err != nil || n != 1 {
+		t.Fatalf(`Cleared keys differ.. Expected %d.. Got %d, %v`, 1, n, err)
+	}
+
+	if status, _ := throttler.Status("10.0.0.1"); status.Limit != 5 {
+		t.Fatalf(`Expected the override to survive the reset.. Got %+v`, status)
+	}
+}
@@ -0,0 +1,34 @@
+package persistent
+
+import (
+	"time"
+
+	"github.com/adelowo/gottle/sharded"
+)
+
+//Option configures a Store
+type Option func(*Store)
+
+//SnapshotInterval is an Option that sets how often the store is written to disk.
+//A zero or negative interval disables periodic snapshots,
+//leaving only the one taken by Close
+func SnapshotInterval(interval time.Duration) Option {
+	return func(s *Store) {
+		s.interval = interval
+	}
+}
+
+//ShardedOptions is an Option that configures the underlying sharded.Store
+func ShardedOptions(opts ...sharded.Option) Option {
+	return func(s *Store) {
+		s.shardedOptions = opts
+	}
+}
+
+//OnError is an Option that sets a function called with the errors
+//periodic snapshots run into, as there is no caller to return them to
+func OnError(fn func(error)) Option {
+	return func(s *Store) {
+		s.onError = fn
+	}
+}
@@ -0,0 +1,189 @@
+//Package persistent provides an in-process store whose state survives restarts.
+//
+//It is a sharded.Store that periodically snapshots every live key to a
+//file on disk and restores them when it is created again, with each key
+//keeping whatever time it had left before expiring. Lockouts obtained
+//before a deploy are still in place after it
+package persistent
+
+import (
+	"encoding/gob"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/adelowo/gottle/sharded"
+)
+
+const (
+	defaultSnapshotInterval = time.Second * 30
+	snapshotVersion         = 1
+)
+
+//ErrUnsupportedSnapshot is returned by New when the snapshot on disk
+//was written in a format this version does not understand
+var ErrUnsupportedSnapshot = errors.New(
+	`persistent: The snapshot was written in an unsupported format`)
+
+//snapshot is what gets written to disk
+type snapshot struct {
+	Version int
+	TakenAt time.Time
+	Entries map[string]sharded.Entry
+}
+
+//Store is a sharded.Store backed by a snapshot file
+type Store struct {
+	*sharded.Store
+
+	path           string
+	interval       time.Duration
+	shardedOptions []sharded.Option
+	onError        func(error)
+
+	mu       sync.Mutex //serializes snapshots
+	stop     chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+//New returns a Store that snapshots to path.
+//If path holds a snapshot from a previous run, every key in it that has
+//not expired yet is restored. A missing file is not an error
+func New(path string, opts ...Option) (*Store, error) {
+	s := &Store{
+		path:     path,
+		interval: defaultSnapshotInterval,
+		onError:  func(error) {},
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	s.Store = sharded.New(s.shardedOptions...)
+
+	if err := s.restore(); err != nil {
+		s.Store.Close()
+		return nil, err
+	}
+
+	if s.interval > 0 {
+		go s.loop()
+	} else {
+		close(s.done)
+	}
+
+	return s, nil
+}
+
+func (s *Store) restore() error {
+	f, err := os.Open(s.path)
+
+	if os.IsNotExist(err) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	defer f.Close()
+
+	snap := new(snapshot)
+
+	if err := gob.NewDecoder(f).Decode(snap); err != nil {
+		return err
+	}
+
+	if snap.Version != snapshotVersion {
+		return ErrUnsupportedSnapshot
+	}
+
+	for key, e := range snap.Entries {
+		s.Store.Restore(key, e)
+	}
+
+	return nil
+}
+
+func (s *Store) loop() {
+	defer close(s.done)
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := s.Snapshot(); err != nil {
+				s.onError(err)
+			}
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+//Snapshot writes every live key to disk.
+//The file is replaced atomically, so a crash halfway through
+//leaves the previous snapshot in place
+func (s *Store) Snapshot() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	snap := &snapshot{
+		Version: snapshotVersion,
+		TakenAt: time.Now(),
+		Entries: make(map[string]sharded.Entry),
+	}
+
+	s.Store.Range(func(key string, e sharded.Entry) bool {
+		snap.Entries[key] = e
+		return true
+	})
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".tmp")
+
+	if err != nil {
+		return err
+	}
+
+	//A no-op once the file has been renamed
+	defer os.Remove(tmp.Name())
+
+	if err := gob.NewEncoder(tmp).Encode(snap); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), s.path)
+}
+
+//Close stops the periodic snapshots and takes a final one
+func (s *Store) Close() error {
+	var err error
+
+	s.stopOnce.Do(func() {
+		close(s.stop)
+		<-s.done
+
+		err = s.Snapshot()
+		s.Store.Close()
+	})
+
+	return err
+}
@@ -0,0 +1,97 @@
+package persistent
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/adelowo/gottle"
+	"github.com/adelowo/gottle/gottletest"
+	"github.com/adelowo/gottle/sharded"
+	"github.com/adelowo/onecache"
+)
+
+var _ onecache.Store = &Store{}
+var _ gottle.LimitCounter = &Store{}
+
+func TestStore_survivesRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gottle.snapshot")
+
+	r := httptest.NewRequest(http.MethodGet, "/oops", nil)
+	r.Header.Set("X-Forwarded-For", "123.456.789.000")
+
+	store, err := New(path, SnapshotInterval(0))
+
+	if err != nil {
+		t.Fatalf(`An error occurred while creating the store.. %v`, err)
+	}
+
+	opts := []gottle.Option{gottle.ThrottleCondition(time.Minute, 3)}
+
+	throttler := gottle.NewOneCacheThrottler(append(opts, gottle.Store(store))...)
+
+	gottletest.AssertLimitedAfter(t, throttler, r, 3)
+
+	store.Set("short", []byte("value"), time.Millisecond)
+
+	if err := store.Close(); err != nil {
+		t.Fatalf(`An error occurred while closing the store.. %v`, err)
+	}
+
+	time.Sleep(time.Millisecond * 5)
+
+	restarted, err := New(path, SnapshotInterval(0))
+
+	if err != nil {
+		t.Fatalf(`An error occurred while restoring the store.. %v`, err)
+	}
+
+	defer restarted.Close()
+
+	throttler = gottle.NewOneCacheThrottler(append(opts, gottle.Store(restarted))...)
+
+	if !throttler.IsRateLimited(r) {
+		t.Fatal(`The lockout is supposed to survive a restart`)
+	}
+
+	if restarted.Has("short") {
+		t.Fatal(`Keys that expired while the store was down are not supposed to be restored`)
+	}
+}
+
+func TestStore_periodicSnapshots(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gottle.snapshot")
+
+	store, err := New(path,
+		SnapshotInterval(time.Millisecond*10),
+		ShardedOptions(sharded.Shards(4)))
+
+	if err != nil {
+		t.Fatalf(`An error occurred while creating the store.. %v`, err)
+	}
+
+	defer store.Close()
+
+	store.Set("key", []byte("value"), time.Minute)
+
+	time.Sleep(time.Millisecond * 50)
+
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf(`Expected a snapshot to have been taken.. %v`, err)
+	}
+}
+
+func TestNew_corruptSnapshot(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gottle.snapshot")
+
+	if err := os.WriteFile(path, []byte("oops"), 0600); err != nil {
+		t.Fatalf(`An error occurred while writing the file.. %v`, err)
+	}
+
+	if _, err := New(path); err == nil {
+		t.Fatal(`An error is supposed to be returned for a corrupt snapshot`)
+	}
+}
@@ -0,0 +1,138 @@
+package gottle
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"time"
+)
+
+//ErrRequestShed is returned when a request is rejected to keep capacity
+//for requests of a higher priority
+var ErrRequestShed = errors.New(
+	`gottle: The request was shed to keep capacity for higher priorities`)
+
+//ErrInvalidClasses is returned when a Shedder is created without
+//classes, or with a class whose share is not above 0 and up to 1
+var ErrInvalidClasses = errors.New(
+	`gottle: A shedder needs at least one class and shares above 0 and up to 1`)
+
+//Class is a priority class of a Shedder
+type Class struct {
+	Name string
+
+	//Share is the part of the limit, above 0 and up to 1, that requests
+	//of the class can use. The higher the priority, the larger the share
+	Share float64
+}
+
+//ClassFunc returns the name of the priority class of a request,
+//based on it's credentials for example
+type ClassFunc func(r *http.Request) string
+
+//Shedder is a limit shared by every request, such as what the service can
+//handle per second, that sheds lower priorities first as it fills up.
+//Requests of a class are rejected once the hits counted in the current
+//window, whatever their class, would go over the class' share of the limit.
+//So with a share of 0.5 for anonymous traffic and 1 for paying customers,
+//anonymous requests are shed when the service is half way to it's capacity
+//and customers keep getting through until it is reached.
+//
+//Hits are counted in fixed windows of the interval, in the store
+type Shedder struct {
+	t       *OnecacheThrottler
+	classOf ClassFunc
+	classes map[string]Class
+
+	//lowest is the class of requests whose class is unknown
+	lowest Class
+}
+
+//NewShedder returns a Shedder that lets limit hits through per interval,
+//classing requests with classOf. Requests of a class that is not in classes
+//get the lowest share. opts configure it like a throttler, such as it's
+//store and reporting. Shedders sharing a store need different names.
+//Candidates and quotas don't apply to it
+func NewShedder(limit int, interval time.Duration, classOf ClassFunc, classes []Class, opts ...Option) (*Shedder, error) {
+	if len(classes) == 0 {
+		return nil, ErrInvalidClasses
+	}
+
+	for _, class := range classes {
+		if class.Share <= 0 || class.Share > 1 {
+			return nil, ErrInvalidClasses
+		}
+	}
+
+	t := NewOneCacheThrottler(append(append([]Option{}, opts...),
+		ThrottleCondition(interval, limit))...)
+
+	t.quota = &quota{every: interval}
+	t.candidate = nil
+
+	s := &Shedder{t: t, classOf: classOf, classes: make(map[string]Class, len(classes))}
+
+	for i, class := range classes {
+		s.classes[class.Name] = class
+
+		if i == 0 || class.Share < s.lowest.Share {
+			s.lowest = class
+		}
+	}
+
+	return s, nil
+}
+
+//Throttle throttles an HTTP request against the share of it's class
+func (s *Shedder) Throttle(r *http.Request) error {
+	return s.ThrottleN(r, defaultThrottledItemIncrement)
+}
+
+//ThrottleN throttles an HTTP request that costs n hits against the share of it's class
+func (s *Shedder) ThrottleN(r *http.Request, n int) error {
+	if n < 1 {
+		return ErrInvalidCost
+	}
+
+	return s.take(r.Context(), s.classOf(r), n)
+}
+
+//ThrottleClass works like Throttle for a request of class
+//that is not an HTTP request
+func (s *Shedder) ThrottleClass(ctx context.Context, class string) error {
+	return s.take(ctx, class, defaultThrottledItemIncrement)
+}
+
+//Clear resets the hits counted in the current window, for every class
+func (s *Shedder) Clear(r *http.Request) error {
+	return s.t.clear(s.bucket(context.Background(), s.lowest))
+}
+
+//take records n hits of class unless they would take the count past the class' share
+func (s *Shedder) take(ctx context.Context, name string, n int) error {
+	class, ok := s.classes[name]
+
+	if !ok {
+		class = s.lowest
+	}
+
+	err := s.t.decide(s.bucket(ctx, class), n)
+
+	if err == ErrClientIsRateLimited {
+		return ErrRequestShed
+	}
+
+	return err
+}
+
+//bucket returns the count every class shares, limited to class' share of it
+func (s *Shedder) bucket(ctx context.Context, class Class) client {
+	c := client{ctx: ctx, key: s.t.name}
+	c.limit, c.interval = s.t.throttleCondition()
+	s.t.windowed(&c)
+
+	c.limit = int(math.Ceil(float64(c.limit) * class.Share))
+
+	return c
+}
@@ -0,0 +1,111 @@
+package gottle
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/adelowo/gottle/sharded"
+)
+
+var _ WeightedThrottler = &Shedder{}
+
+var classes = []Class{
+	{Name: "anonymous", Share: 0.5},
+	{Name: "free", Share: 0.8},
+	{Name: "paid", Share: 1},
+}
+
+func TestShedder(t *testing.T) {
+	clock := &fixedClock{time.Date(2017, time.March, 1, 12, 0, 0, 0, time.UTC)}
+
+	s, _ := NewShedder(10, time.Second, nil, classes, Clock(clock), Store(sharded.New()))
+
+	take := func(class string, expected ...error) {
+		t.Helper()
+
+		for i, e := range expected {
+			if err := s.ThrottleClass(context.Background(), class); err != e {
+				t.Fatalf(`Errors differ for request %d of class %s.. Expected %v.. Got %v`,
+					i+1, class, e, err)
+			}
+		}
+	}
+
+	take("anonymous", nil, nil, nil, nil, nil, ErrRequestShed)
+
+	//Classes that are not known get the lowest share
+	take("robots", ErrRequestShed)
+
+	take("free", nil, nil, nil, ErrRequestShed)
+	take("paid", nil, nil, ErrRequestShed)
+
+	clock.t = clock.t.Add(time.Second)
+
+	take("anonymous", nil)
+}
+
+func TestShedder_Middleware(t *testing.T) {
+	classOf := func(r *http.Request) string {
+		if r.Header.Get("Authorization") != "" {
+			return "paid"
+		}
+
+		return "anonymous"
+	}
+
+	s, _ := NewShedder(2, time.Minute, classOf, classes)
+
+	handler := Middleware(s)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	tableTests := []struct {
+		authorization string
+		code          int
+	}{
+		{"", http.StatusNoContent},
+		{"", http.StatusServiceUnavailable},
+		{"Bearer oops", http.StatusNoContent},
+		{"Bearer oops", http.StatusServiceUnavailable},
+	}
+
+	for i, v := range tableTests {
+		r := httptest.NewRequest(http.MethodGet, "/oops", nil)
+		r.Header.Set("Authorization", v.authorization)
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+
+		if w.Code != v.code {
+			t.Fatalf(`Status codes differ for request %d.. Expected %d.. Got %d`, i+1, v.code, w.Code)
+		}
+	}
+}
+
+func TestShedder_shadow(t *testing.T) {
+	s, _ := NewShedder(1, time.Minute, nil, classes, Shadow(true))
+
+	for i := 0; i < 3; i++ {
+		if err := s.ThrottleClass(context.Background(), "anonymous"); err != nil {
+			t.Fatalf(`Shedders in shadow mode are not supposed to reject requests.. Got %v`, err)
+		}
+	}
+}
+
+func TestNewShedder_invalid(t *testing.T) {
+	tableTests := [][]Class{
+		nil,
+		{{Name: "anonymous", Share: 0}},
+		{{Name: "anonymous", Share: -0.5}, {Name: "paid", Share: 1}},
+		{{Name: "paid", Share: 1.5}},
+	}
+
+	for i, v := range tableTests {
+		if _, err := NewShedder(10, time.Second, nil, v); err != ErrInvalidClasses {
+			t.Fatalf(`Errors differ for classes %d.. Expected %v.. Got %v`, i+1, ErrInvalidClasses, err)
+		}
+	}
+}
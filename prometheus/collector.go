@@ -0,0 +1,108 @@
+//Package prometheus exposes gottle's measurements as Prometheus metrics.
+//
+//Metrics are labelled by the name of the throttler, never by key,
+//so the number of series stays bounded no matter how many clients there are
+package prometheus
+
+import (
+	"strconv"
+	"time"
+
+	prom "github.com/prometheus/client_golang/prometheus"
+)
+
+const defaultNamespace = "gottle"
+
+//Collector implements gottle.MetricsRecorder, gottle.LimitRecorder and prometheus.Collector.
+//Pass it to the throttler with the gottle.Metrics option and register it
+//with a prometheus.Registerer
+type Collector struct {
+	namespace string
+	buckets   []float64
+
+	decisions    *prom.CounterVec
+	storeErrors  *prom.CounterVec
+	storeLatency *prom.HistogramVec
+	limits       *prom.GaugeVec
+}
+
+//New returns a Collector
+func New(opts ...Option) *Collector {
+	c := &Collector{
+		namespace: defaultNamespace,
+		buckets:   prom.DefBuckets,
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	c.decisions = prom.NewCounterVec(prom.CounterOpts{
+		Namespace: c.namespace,
+		Name:      "decisions_total",
+		Help:      "Number of throttling decisions, by limiter, outcome and whether they were made in shadow mode.",
+	}, []string{"limiter", "decision", "shadow"})
+
+	c.storeErrors = prom.NewCounterVec(prom.CounterOpts{
+		Namespace: c.namespace,
+		Name:      "store_errors_total",
+		Help:      "Number of failed store operations, by limiter and operation.",
+	}, []string{"limiter", "op"})
+
+	c.storeLatency = prom.NewHistogramVec(prom.HistogramOpts{
+		Namespace: c.namespace,
+		Name:      "store_latency_seconds",
+		Help:      "Latency of store operations, by limiter and operation.",
+		Buckets:   c.buckets,
+	}, []string{"limiter", "op"})
+
+	c.limits = prom.NewGaugeVec(prom.GaugeOpts{
+		Namespace: c.namespace,
+		Name:      "limit",
+		Help:      "Current limit of the throttler, by limiter.",
+	}, []string{"limiter"})
+
+	return c
+}
+
+//Decision records the outcome of a call to Throttle
+func (c *Collector) Decision(name string, allowed, shadow bool) {
+	decision := "denied"
+
+	if allowed {
+		decision = "allowed"
+	}
+
+	c.decisions.WithLabelValues(name, decision, strconv.FormatBool(shadow)).Inc()
+}
+
+//StoreError records a failed operation on the store
+func (c *Collector) StoreError(name, op string) {
+	c.storeErrors.WithLabelValues(name, op).Inc()
+}
+
+//StoreLatency records how long an operation on the store took
+func (c *Collector) StoreLatency(name, op string, d time.Duration) {
+	c.storeLatency.WithLabelValues(name, op).Observe(d.Seconds())
+}
+
+//Limit records the current limit of a throttler
+func (c *Collector) Limit(name string, limit int) {
+	c.limits.WithLabelValues(name).Set(float64(limit))
+}
+
+//Describe sends the descriptors of every metric to ch
+func (c *Collector) Describe(ch chan<- *prom.Desc) {
+	c.decisions.Describe(ch)
+	c.storeErrors.Describe(ch)
+	c.storeLatency.Describe(ch)
+	c.limits.Describe(ch)
+}
+
+//Collect sends every metric to ch
+func (c *Collector) Collect(ch chan<- prom.Metric) {
+	c.decisions.Collect(ch)
+	c.storeErrors.Collect(ch)
+	c.storeLatency.Collect(ch)
+	c.limits.Collect(ch)
+}
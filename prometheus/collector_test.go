@@ -0,0 +1,101 @@
+package prometheus
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/adelowo/gottle"
+	"github.com/adelowo/gottle/gottletest"
+	prom "github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+var _ gottle.MetricsRecorder = &Collector{}
+var _ prom.Collector = &Collector{}
+
+func TestCollector(t *testing.T) {
+	collector := New()
+
+	registry := prom.NewRegistry()
+
+	if err := registry.Register(collector); err != nil {
+		t.Fatalf(`An error occurred while registering the collector.. %v`, err)
+	}
+
+	store := gottletest.NewStore(nil)
+
+	throttler := gottle.NewOneCacheThrottler(
+		gottle.Name("login"),
+		gottle.Metrics(collector),
+		gottle.Store(store),
+		gottle.ThrottleCondition(time.Minute, 2))
+
+	r := httptest.NewRequest(http.MethodGet, "/oops", nil)
+	r.Header.Set("X-Forwarded-For", "123.456.789.000")
+
+	for i := 0; i < 3; i++ {
+		throttler.Throttle(r)
+	}
+
+	store.Fail(gottletest.OpSet, errors.New("oops"))
+	throttler.Clear(r)
+	throttler.Throttle(r)
+
+	expected := `
+# HELP gottle_decisions_total Number of throttling decisions, by limiter, outcome and whether they were made in shadow mode.
+# TYPE gottle_decisions_total counter
+gottle_decisions_total{decision="allowed",limiter="login",shadow="false"} 2
+gottle_decisions_total{decision="denied",limiter="login",shadow="false"} 1
+# HELP gottle_store_errors_total Number of failed store operations, by limiter and operation.
+# TYPE gottle_store_errors_total counter
+gottle_store_errors_total{limiter="login",op="set"} 1
+`
+
+	err := testutil.GatherAndCompare(registry, strings.NewReader(expected),
+		"gottle_decisions_total", "gottle_store_errors_total")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n := testutil.CollectAndCount(collector, "gottle_store_latency_seconds"); n == 0 {
+		t.Fatal(`Expected the latency of store operations to have been recorded`)
+	}
+}
+
+func TestNamespace(t *testing.T) {
+	collector := New(Namespace("api"))
+
+	collector.Decision("login", true, false)
+
+	if n := testutil.CollectAndCount(collector, "api_decisions_total"); n != 1 {
+		t.Fatalf(`Expected %d series under the custom namespace.. Got %d`, 1, n)
+	}
+}
+
+var _ gottle.LimitRecorder = &Collector{}
+
+func TestCollector_Limit(t *testing.T) {
+	collector := New()
+
+	throttler := gottle.NewOneCacheThrottler(
+		gottle.Name("login"),
+		gottle.Metrics(collector),
+		gottle.ThrottleCondition(time.Minute, 5))
+
+	throttler.SetThrottleCondition(time.Minute, 8)
+
+	expected := `
+# HELP gottle_limit Current limit of the throttler, by limiter.
+# TYPE gottle_limit gauge
+gottle_limit{limiter="login"} 8
+`
+
+	if err := testutil.CollectAndCompare(collector, strings.NewReader(expected), "gottle_limit"); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,20 @@
+package prometheus
+
+//Option configures a Collector
+type Option func(*Collector)
+
+//Namespace is an Option that sets the namespace metric names are prefixed with.
+//It defaults to "gottle"
+func Namespace(namespace string) Option {
+	return func(c *Collector) {
+		c.namespace = namespace
+	}
+}
+
+//Buckets is an Option that sets the buckets of the store latency histogram, in seconds.
+//It defaults to prometheus.DefBuckets
+func Buckets(buckets []float64) Option {
+	return func(c *Collector) {
+		c.buckets = buckets
+	}
+}
@@ -0,0 +1,140 @@
+package gottle
+
+import (
+	"strconv"
+	"time"
+)
+
+//windowSeparator separates a key from the calendar window
+//it's hits are counted in, as in "1.2.3.4@2017-03-01"
+const windowSeparator = "@"
+
+//windowFormat is how the start of a calendar window is written in keys
+const windowFormat = "2006-01-02"
+
+//Period is the length of the calendar windows a quota is counted in
+type Period int
+
+//Periods a quota can be counted in
+const (
+	//Day windows start at midnight
+	Day Period = iota + 1
+
+	//Week windows start on Monday at midnight
+	Week
+
+	//Month windows start on the 1st at midnight
+	Month
+)
+
+//window returns the start and end of the window of p that now falls in,
+//in loc
+func (p Period) window(now time.Time, loc *time.Location) (time.Time, time.Time) {
+	y, m, d := now.In(loc).Date()
+
+	switch p {
+	case Week:
+		start := time.Date(y, m, d-(int(now.In(loc).Weekday())+6)%7, 0, 0, 0, 0, loc)
+		return start, start.AddDate(0, 0, 7)
+
+	case Month:
+		start := time.Date(y, m, 1, 0, 0, 0, 0, loc)
+		return start, start.AddDate(0, 1, 0)
+	}
+
+	start := time.Date(y, m, d, 0, 0, 0, 0, loc)
+
+	return start, start.AddDate(0, 0, 1)
+}
+
+//quota holds the windows of a throttler in quota mode
+type quota struct {
+	period   Period
+	location *time.Location
+
+	//every is the length of fixed windows, which a Global uses
+	//rather than calendar ones
+	every time.Duration
+}
+
+//window returns the start and end of the window now falls in
+func (q *quota) window(now time.Time) (time.Time, time.Time) {
+	if q.every > 0 {
+		start := now.Truncate(q.every)
+		return start, start.Add(q.every)
+	}
+
+	return q.period.window(now, q.location)
+}
+
+//label returns how the window starting at start is written in keys
+func (q *quota) label(start time.Time) string {
+	if q.every > 0 {
+		return strconv.FormatInt(start.UnixMilli(), 10)
+	}
+
+	return start.Format(windowFormat)
+}
+
+//windowed moves c to the window it currently is in,
+//for throttlers in quota mode
+func (t *OnecacheThrottler) windowed(c *client) {
+	if t.quota == nil {
+		return
+	}
+
+	start, end := t.quota.window(t.now())
+
+	c.window = t.quota.label(start)
+	c.end = end
+
+	//Every hit of the window counts until it ends, and the window being
+	//in the key means the hits of the previous one don't carry over
+	c.interval = end.Sub(start)
+}
+
+//Usage is the use a key has made of it's quota
+type Usage struct {
+	Key       string `json:"key"`
+	Used      int    `json:"used"`
+	Limit     int    `json:"limit"`
+	Remaining int    `json:"remaining"`
+
+	//Start and ResetAt are the bounds of the current calendar window.
+	//Outside of quota mode, Start is the zero time and ResetAt is
+	//when the hits recorded for the key expire, as in KeyStatus
+	Start   time.Time `json:"start"`
+	ResetAt time.Time `json:"reset_at"`
+}
+
+//Usage returns how much of it's quota key has used and when it resets,
+//for billing pages and the like
+func (t *OnecacheThrottler) Usage(key string) (Usage, error) {
+	status, err := t.Status(key)
+
+	usage := Usage{
+		Key:       key,
+		Used:      status.Hits,
+		Limit:     status.Limit,
+		Remaining: status.Remaining,
+		ResetAt:   status.ResetAt,
+	}
+
+	if t.quota != nil {
+		usage.Start, usage.ResetAt = t.quota.window(t.now())
+	}
+
+	return usage, err
+}
+
+//NextReset returns when the current calendar window ends and every quota
+//is reset. It is the zero time for throttlers that are not in quota mode
+func (t *OnecacheThrottler) NextReset() time.Time {
+	if t.quota == nil {
+		return time.Time{}
+	}
+
+	_, end := t.quota.window(t.now())
+
+	return end
+}
@@ -0,0 +1,132 @@
+package gottle
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestPeriod_window(t *testing.T) {
+	loc := time.FixedZone("UTC+2", 2*60*60)
+
+	//A Friday, already the next day in loc
+	now := time.Date(2017, time.March, 31, 23, 0, 0, 0, time.UTC)
+
+	tt := []struct {
+		period     Period
+		start, end time.Time
+	}{
+		{Day, time.Date(2017, time.April, 1, 0, 0, 0, 0, loc), time.Date(2017, time.April, 2, 0, 0, 0, 0, loc)},
+		{Week, time.Date(2017, time.March, 27, 0, 0, 0, 0, loc), time.Date(2017, time.April, 3, 0, 0, 0, 0, loc)},
+		{Month, time.Date(2017, time.April, 1, 0, 0, 0, 0, loc), time.Date(2017, time.May, 1, 0, 0, 0, 0, loc)},
+	}
+
+	for _, v := range tt {
+		start, end := v.period.window(now, loc)
+
+		if !start.Equal(v.start) || !end.Equal(v.end) {
+			t.Fatalf(`Windows differ for period %d.. Expected %v - %v.. Got %v - %v`,
+				v.period, v.start, v.end, start, end)
+		}
+	}
+}
+
+func TestOnecacheThrottler_Quota(t *testing.T) {
+	clock := &fixedClock{time.Date(2017, time.March, 28, 10, 0, 0, 0, time.UTC)}
+
+	throttler := NewOneCacheThrottler(
+		Clock(clock),
+		Quota(Month, nil),
+		ThrottleCondition(time.Minute, 3))
+
+	r := httptest.NewRequest(http.MethodGet, "/oops", nil)
+	r.Header.Set(xForwardedFor, "123.456.789.000")
+
+	for i := 0; i < 3; i++ {
+		if err := throttler.Throttle(r); err != nil {
+			t.Fatalf(`An error occurred while throttling the request .. %v`, err)
+		}
+
+		clock.t = clock.t.Add(time.Hour * 24)
+	}
+
+	//The interval has long passed, but the month has not
+	if err := throttler.Throttle(r); err != ErrClientIsRateLimited {
+		t.Fatalf(`Errors differ.. Expected %v.. Got %v`, ErrClientIsRateLimited, err)
+	}
+
+	reset := time.Date(2017, time.April, 1, 0, 0, 0, 0, time.UTC)
+
+	status, _ := throttler.Status("123.456.789.000")
+
+	if !status.Limited || !status.ResetAt.Equal(reset) {
+		t.Fatalf(`Expected the key to be limited until %v.. Got %+v`, reset, status)
+	}
+
+	if next := throttler.NextReset(); !next.Equal(reset) {
+		t.Fatalf(`Next resets differ.. Expected %v.. Got %v`, reset, next)
+	}
+
+	clock.t = reset
+
+	if err := throttler.Throttle(r); err != nil {
+		t.Fatalf(`Expected the quota to have been reset.. Got %v`, err)
+	}
+
+	usage, err := throttler.Usage("123.456.789.000")
+
+	if err != nil {
+		t.Fatalf(`An error occurred while fetching the usage.. %v`, err)
+	}
+
+	expected := Usage{
+		Key:       "123.456.789.000",
+		Used:      1,
+		Limit:     3,
+		Remaining: 2,
+		Start:     reset,
+		ResetAt:   time.Date(2017, time.May, 1, 0, 0, 0, 0, time.UTC),
+	}
+
+	if usage != expected {
+		t.Fatalf(`Usage differs.. Expected %+v.. Got %+v`, expected, usage)
+	}
+}
+
+func TestOnecacheThrottler_Quota_Keys(t *testing.T) {
+	clock := &fixedClock{time.Date(2017, time.March, 31, 10, 0, 0, 0, time.UTC)}
+
+	throttler := NewOneCacheThrottler(
+		Clock(clock),
+		Store(newEnumeratingStore()),
+		Quota(Day, nil),
+		ThrottleCondition(time.Minute, 3))
+
+	throttleKeys(throttler, map[string]int{"10.0.0.1": 1})
+
+	clock.t = clock.t.Add(time.Hour * 24)
+
+	throttleKeys(throttler, map[string]int{"10.0.0.2": 1})
+
+	var keys []string
+
+	err := throttler.Keys("", false, func(status KeyStatus) bool {
+		keys = append(keys, status.Key)
+		return true
+	})
+
+	if err != nil {
+		t.Fatalf(`An error occurred while listing the keys.. %v`, err)
+	}
+
+	if len(keys) != 1 || keys[0] != "10.0.0.2" {
+		t.Fatalf(`Expected only the keys of the current window.. Got %v`, keys)
+	}
+}
+
+func TestOnecacheThrottler_NextReset(t *testing.T) {
+	if next := NewOneCacheThrottler().NextReset(); !next.IsZero() {
+		t.Fatalf(`Expected no reset outside of quota mode.. Got %v`, next)
+	}
+}
@@ -0,0 +1,22 @@
+package redis
+
+import "time"
+
+//Option configures a Store
+type Option func(*Store)
+
+//Prefix is an Option that sets the prefix added to every key.
+//It defaults to "gottle:"
+func Prefix(prefix string) Option {
+	return func(s *Store) {
+		s.prefix = prefix
+	}
+}
+
+//Timeout is an Option that bounds how long a single call to Redis can take.
+//By default calls are only bounded by the client's own timeouts
+func Timeout(timeout time.Duration) Option {
+	return func(s *Store) {
+		s.timeout = timeout
+	}
+}
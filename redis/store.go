@@ -0,0 +1,264 @@
+//Package redis provides a Redis backed store for gottle.
+//
+//Unlike the onecache Redis adapter, counters are kept natively as hashes
+//and each throttling operation runs as a single Lua script through EVALSHA.
+//That makes every call one round trip and atomic across all
+//the instances sharing the Redis server
+package redis
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/adelowo/onecache"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+const defaultPrefix = "gottle:"
+
+//Counters are hashes with these fields
+const (
+	fieldHits = "h"
+	fieldLast = "l"
+)
+
+//incrScript adds ARGV[1] hits to the counter at KEYS[1],
+//recording ARGV[2] (unix ms) as the most recent hit and
+//expiring the counter ARGV[3] milliseconds after it
+var incrScript = goredis.NewScript(`
+local hits = redis.call('HINCRBY', KEYS[1], 'h', ARGV[1])
+redis.call('HSET', KEYS[1], 'l', ARGV[2])
+redis.call('PEXPIRE', KEYS[1], ARGV[3])
+return hits
+`)
+
+//incrUnderScript works like incrScript but leaves the counter alone if adding
+//ARGV[1] hits would take it past ARGV[4] and it's most recent hit is within ARGV[3] milliseconds.
+//It returns the hits and 1 if they were added, 0 if not
+var incrUnderScript = goredis.NewScript(`
+local state = redis.call('HMGET', KEYS[1], 'h', 'l')
+local hits = tonumber(state[1] or '0')
+local last = tonumber(state[2] or '0')
+local n = tonumber(ARGV[1])
+if n > tonumber(ARGV[4]) or (hits + n > tonumber(ARGV[4]) and tonumber(ARGV[2]) - last <= tonumber(ARGV[3])) then
+	return {hits, 0}
+end
+hits = redis.call('HINCRBY', KEYS[1], 'h', ARGV[1])
+redis.call('HSET', KEYS[1], 'l', ARGV[2])
+redis.call('PEXPIRE', KEYS[1], ARGV[3])
+return {hits, 1}
+`)
+
+//decrScript takes ARGV[1] hits back from the counter at KEYS[1],
+//leaving it's expiry alone, and returns the hits left
+var decrScript = goredis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 0 then
+	return 0
+end
+local hits = redis.call('HINCRBY', KEYS[1], 'h', -tonumber(ARGV[1]))
+if hits < 0 then
+	redis.call('HSET', KEYS[1], 'h', 0)
+	return 0
+end
+return hits
+`)
+
+//getScript fetches a plain value, or the hits if KEYS[1] is a counter
+var getScript = goredis.NewScript(`
+if redis.call('TYPE', KEYS[1]).ok == 'hash' then
+	return redis.call('HGET', KEYS[1], 'h')
+end
+return redis.call('GET', KEYS[1])
+`)
+
+//Store is a Redis backed implementation of onecache.Store, gottle.Counter,
+//gottle.LimitCounter, gottle.Decrementer and gottle.Enumerator
+type Store struct {
+	client  goredis.UniversalClient
+	prefix  string
+	timeout time.Duration
+}
+
+//New returns a Store that talks to Redis through client
+func New(client goredis.UniversalClient, opts ...Option) *Store {
+	s := &Store{
+		client: client,
+		prefix: defaultPrefix,
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+func (s *Store) key(key string) string {
+	return s.prefix + key
+}
+
+func (s *Store) context() (context.Context, context.CancelFunc) {
+	if s.timeout > 0 {
+		return context.WithTimeout(context.Background(), s.timeout)
+	}
+
+	return context.WithCancel(context.Background())
+}
+
+//Set stores data under key
+func (s *Store) Set(key string, data []byte, expires time.Duration) error {
+	ctx, cancel := s.context()
+	defer cancel()
+
+	return s.client.Set(ctx, s.key(key), data, expires).Err()
+}
+
+//Get fetches the data stored under key.
+//For counters, the number of hits is returned as a decimal string
+func (s *Store) Get(key string) ([]byte, error) {
+	ctx, cancel := s.context()
+	defer cancel()
+
+	val, err := getScript.Run(ctx, s.client, []string{s.key(key)}).Text()
+
+	if err == goredis.Nil {
+		return nil, onecache.ErrCacheMiss
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(val), nil
+}
+
+//Delete removes key
+func (s *Store) Delete(key string) error {
+	ctx, cancel := s.context()
+	defer cancel()
+
+	return s.client.Del(ctx, s.key(key)).Err()
+}
+
+//Flush removes every key with the store's prefix, glob characters
+//in it included. Other keys in the database are left untouched
+func (s *Store) Flush() error {
+	ctx, cancel := s.context()
+	defer cancel()
+
+	iter := s.client.Scan(ctx, 0, globEscaper.Replace(s.prefix)+"*", 0).Iterator()
+
+	for iter.Next(ctx) {
+		if err := s.client.Del(ctx, iter.Val()).Err(); err != nil {
+			return err
+		}
+	}
+
+	return iter.Err()
+}
+
+//Has checks if key exists
+func (s *Store) Has(key string) bool {
+	ctx, cancel := s.context()
+	defer cancel()
+
+	n, err := s.client.Exists(ctx, s.key(key)).Result()
+
+	return err == nil && n > 0
+}
+
+//globEscaper escapes the characters SCAN treats as a pattern
+var globEscaper = strings.NewReplacer(
+	`\`, `\\`, `*`, `\*`, `?`, `\?`, `[`, `\[`, `]`, `\]`)
+
+//Enumerate calls fn for every key starting with prefix.
+//Keys are passed to fn without the store's own prefix
+func (s *Store) Enumerate(prefix string, fn func(key string) bool) error {
+	ctx, cancel := s.context()
+	defer cancel()
+
+	iter := s.client.Scan(ctx, 0, globEscaper.Replace(s.prefix+prefix)+"*", 0).Iterator()
+
+	for iter.Next(ctx) {
+		if !fn(strings.TrimPrefix(iter.Val(), s.prefix)) {
+			return nil
+		}
+	}
+
+	return iter.Err()
+}
+
+//Incr adds n hits to key.
+//The key expires ttl after the last time it was incremented
+func (s *Store) Incr(key string, n int, now time.Time, ttl time.Duration) (int, error) {
+	ctx, cancel := s.context()
+	defer cancel()
+
+	hits, err := incrScript.Run(ctx, s.client, []string{s.key(key)},
+		n, now.UnixMilli(), ttl.Milliseconds()).Int()
+
+	if err != nil {
+		return 0, err
+	}
+
+	return hits, nil
+}
+
+//IncrUnder adds n hits to key unless they would take it past limit
+//with it's most recent hit no older than ttl
+func (s *Store) IncrUnder(key string, n, limit int, now time.Time, ttl time.Duration) (int, bool, error) {
+	ctx, cancel := s.context()
+	defer cancel()
+
+	res, err := incrUnderScript.Run(ctx, s.client, []string{s.key(key)},
+		n, now.UnixMilli(), ttl.Milliseconds(), limit).Int64Slice()
+
+	if err != nil {
+		return 0, false, err
+	}
+
+	return int(res[0]), res[1] == 1, nil
+}
+
+//Decr takes n hits back from key, leaving it's expiry as it is,
+//and returns the hits left
+func (s *Store) Decr(key string, n int) (int, error) {
+	ctx, cancel := s.context()
+	defer cancel()
+
+	return decrScript.Run(ctx, s.client, []string{s.key(key)}, n).Int()
+}
+
+//Count returns the hits and most recent hit recorded for key
+func (s *Store) Count(key string) (int, time.Time, error) {
+	ctx, cancel := s.context()
+	defer cancel()
+
+	vals, err := s.client.HMGet(ctx, s.key(key), fieldHits, fieldLast).Result()
+
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+
+	if vals[0] == nil {
+		return 0, time.Time{}, onecache.ErrCacheMiss
+	}
+
+	hits, err := strconv.Atoi(vals[0].(string))
+
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+
+	var last int64
+
+	if vals[1] != nil {
+		if last, err = strconv.ParseInt(vals[1].(string), 10, 64); err != nil {
+			return 0, time.Time{}, err
+		}
+	}
+
+	return hits, time.UnixMilli(last), nil
+}
@@ -0,0 +1,300 @@
+package redis
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/adelowo/gottle"
+	"github.com/adelowo/gottle/gottletest"
+	"github.com/adelowo/onecache"
+	"github.com/alicebob/miniredis/v2"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+var _ onecache.Store = &Store{}
+var _ gottle.Counter = &Store{}
+var _ gottle.LimitCounter = &Store{}
+var _ gottle.Decrementer = &Store{}
+var _ gottle.Enumerator = &Store{}
+
+//roundTrips is a go-redis hook that counts the commands sent to the server
+type roundTrips struct {
+	n int
+}
+
+func (h *roundTrips) DialHook(next goredis.DialHook) goredis.DialHook {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return next(ctx, network, addr)
+	}
+}
+
+func (h *roundTrips) ProcessHook(next goredis.ProcessHook) goredis.ProcessHook {
+	return func(ctx context.Context, cmd goredis.Cmder) error {
+		h.n++
+		return next(ctx, cmd)
+	}
+}
+
+func (h *roundTrips) ProcessPipelineHook(next goredis.ProcessPipelineHook) goredis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []goredis.Cmder) error {
+		h.n++
+		return next(ctx, cmds)
+	}
+}
+
+func setUp(t *testing.T) (*Store, *miniredis.Miniredis, *roundTrips) {
+	mr := miniredis.RunT(t)
+
+	hook := new(roundTrips)
+
+	client := goredis.NewClient(&goredis.Options{Addr: mr.Addr()})
+	client.AddHook(hook)
+	t.Cleanup(func() { client.Close() })
+
+	return New(client), mr, hook
+}
+
+func TestStore_SetGet(t *testing.T) {
+	store, mr, _ := setUp(t)
+
+	if err := store.Set("key", []byte("value"), time.Minute); err != nil {
+		t.Fatalf(`An error occurred while setting the key.. %v`, err)
+	}
+
+	if !mr.Exists(defaultPrefix + "key") {
+		t.Fatal(`Expected the key to have been prefixed`)
+	}
+
+	buf, err := store.Get("key")
+
+	if err != nil || string(buf) != "value" {
+		t.Fatalf(`Data differs.. Expected %s.. Got %s, %v`, "value", buf, err)
+	}
+
+	if err := store.Delete("key"); err != nil {
+		t.Fatalf(`An error occurred while deleting the key.. %v`, err)
+	}
+
+	if store.Has("key") {
+		t.Fatal(`The key is not supposed to exist after it has been deleted`)
+	}
+
+	if _, err := store.Get("key"); err != onecache.ErrCacheMiss {
+		t.Fatalf(`Errors differ.. Expected %v.. Got %v`, onecache.ErrCacheMiss, err)
+	}
+}
+
+func TestStore_Flush(t *testing.T) {
+	store, mr, _ := setUp(t)
+
+	mr.Set("unrelated", "value")
+
+	store.Set("one", []byte("1"), time.Minute)
+	store.Incr("two", 1, time.Now(), time.Minute)
+
+	if err := store.Flush(); err != nil {
+		t.Fatalf(`An error occurred while flushing the store.. %v`, err)
+	}
+
+	if store.Has("one") || store.Has("two") {
+		t.Fatal(`Expected every prefixed key to have been removed`)
+	}
+
+	if !mr.Exists("unrelated") {
+		t.Fatal(`Keys without the prefix are not supposed to be removed`)
+	}
+}
+
+func TestStore_Flush_globPrefix(t *testing.T) {
+	mr := miniredis.RunT(t)
+
+	client := goredis.NewClient(&goredis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	store := New(client, Prefix("app*:"))
+
+	//Would match app*: as a pattern
+	mr.Set("application:key", "value")
+
+	store.Set("one", []byte("1"), time.Minute)
+
+	if err := store.Flush(); err != nil {
+		t.Fatalf(`An error occurred while flushing the store.. %v`, err)
+	}
+
+	if store.Has("one") {
+		t.Fatal(`Expected every prefixed key to have been removed`)
+	}
+
+	if !mr.Exists("application:key") {
+		t.Fatal(`Keys matching the prefix as a pattern are not supposed to be removed`)
+	}
+}
+
+func TestStore_Enumerate(t *testing.T) {
+	store, mr, _ := setUp(t)
+
+	mr.Set("login-unrelated", "value")
+
+	store.Incr("login-1", 1, time.Now(), time.Minute)
+	store.Set("login-2", []byte("2"), time.Minute)
+	store.Incr("login*", 1, time.Now(), time.Minute)
+	store.Incr("api-1", 1, time.Now(), time.Minute)
+
+	keys := make(map[string]bool)
+
+	err := store.Enumerate("login-", func(key string) bool {
+		keys[key] = true
+		return true
+	})
+
+	if err != nil {
+		t.Fatalf(`An error occurred while enumerating the keys.. %v`, err)
+	}
+
+	if len(keys) != 2 || !keys["login-1"] || !keys["login-2"] {
+		t.Fatalf(`Keys differ.. Expected %v.. Got %v`, []string{"login-1", "login-2"}, keys)
+	}
+
+	keys = make(map[string]bool)
+
+	//Glob characters in the prefix are matched literally
+	store.Enumerate("login*", func(key string) bool {
+		keys[key] = true
+		return true
+	})
+
+	if len(keys) != 1 || !keys["login*"] {
+		t.Fatalf(`Keys differ.. Expected %v.. Got %v`, []string{"login*"}, keys)
+	}
+}
+
+func TestStore_Incr(t *testing.T) {
+	store, mr, _ := setUp(t)
+
+	now := time.Now().Truncate(time.Millisecond)
+
+	for i := 1; i <= 3; i++ {
+		hits, err := store.Incr("key", 2, now, time.Minute)
+
+		if err != nil || hits != i*2 {
+			t.Fatalf(`Hits differ.. Expected %d.. Got %d, %v`, i*2, hits, err)
+		}
+	}
+
+	hits, last, err := store.Count("key")
+
+	if err != nil || hits != 6 || !last.Equal(now) {
+		t.Fatalf(`Count differs.. Expected (%d, %v).. Got (%d, %v, %v)`,
+			6, now, hits, last, err)
+	}
+
+	if buf, err := store.Get("key"); err != nil || string(buf) != "6" {
+		t.Fatalf(`Expected the hits as a string.. Got %s, %v`, buf, err)
+	}
+
+	mr.FastForward(time.Minute + time.Second)
+
+	if _, _, err := store.Count("key"); err != onecache.ErrCacheMiss {
+		t.Fatalf(`Errors differ.. Expected %v.. Got %v`, onecache.ErrCacheMiss, err)
+	}
+}
+
+func TestStore_IncrUnder(t *testing.T) {
+	store, _, _ := setUp(t)
+
+	now := time.Now()
+
+	for i := 1; i <= 3; i++ {
+		hits, added, err := store.IncrUnder("key", 1, 3, now, time.Minute)
+
+		if err != nil || !added || hits != i {
+			t.Fatalf(`Expected hit %d to be added.. Got %d, %v, %v`, i, hits, added, err)
+		}
+	}
+
+	hits, added, err := store.IncrUnder("key", 1, 3, now, time.Minute)
+
+	if err != nil || added || hits != 3 {
+		t.Fatalf(`Expected the limit to be enforced.. Got %d, %v, %v`, hits, added, err)
+	}
+}
+
+func TestStore_IncrUnder_cost(t *testing.T) {
+	store, _, _ := setUp(t)
+
+	now := time.Now()
+
+	if hits, added, err := store.IncrUnder("key", 7, 10, now, time.Minute); err != nil || !added || hits != 7 {
+		t.Fatalf(`Expected the hits to be added.. Got %d, %v, %v`, hits, added, err)
+	}
+
+	//Hits that would go past the limit are not added at all
+	if hits, added, err := store.IncrUnder("key", 4, 10, now, time.Minute); err != nil || added || hits != 7 {
+		t.Fatalf(`Expected the limit to be enforced.. Got %d, %v, %v`, hits, added, err)
+	}
+
+	if hits, added, err := store.IncrUnder("key", 3, 10, now, time.Minute); err != nil || !added || hits != 10 {
+		t.Fatalf(`Expected the hits to be added.. Got %d, %v, %v`, hits, added, err)
+	}
+
+	if hits, added, err := store.IncrUnder("other", 11, 10, now, time.Minute); err != nil || added || hits != 0 {
+		t.Fatalf(`Expected more hits than the limit to never be added.. Got %d, %v, %v`, hits, added, err)
+	}
+}
+
+func TestStore_Decr(t *testing.T) {
+	store, _, _ := setUp(t)
+
+	if hits, err := store.Decr("key", 1); err != nil || hits != 0 {
+		t.Fatalf(`Expected a missing key to be left alone.. Got %d, %v`, hits, err)
+	}
+
+	now := time.Now()
+
+	store.Incr("key", 5, now, time.Minute)
+
+	if hits, err := store.Decr("key", 2); err != nil || hits != 3 {
+		t.Fatalf(`Expected %d hits to be left.. Got %d, %v`, 3, hits, err)
+	}
+
+	if hits, err := store.Decr("key", 5); err != nil || hits != 0 {
+		t.Fatalf(`Expected the hits not to go below zero.. Got %d, %v`, hits, err)
+	}
+
+	if hits, _, err := store.Count("key"); err != nil || hits != 0 {
+		t.Fatalf(`Expected the key to be kept with no hits.. Got %d, %v`, hits, err)
+	}
+}
+
+func TestStore_withThrottler(t *testing.T) {
+	store, _, hook := setUp(t)
+
+	r := httptest.NewRequest(http.MethodGet, "/oops", nil)
+	r.Header.Set("X-Forwarded-For", "123.456.789.000")
+
+	throttler := gottle.NewOneCacheThrottler(
+		gottle.Store(store),
+		gottle.ThrottleCondition(time.Minute, 5))
+
+	//The first call loads the script into Redis
+	if err := throttler.Throttle(r); err != nil {
+		t.Fatalf(`An error occurred while throttling the request.. %v`, err)
+	}
+
+	before := hook.n
+
+	if err := throttler.Throttle(r); err != nil {
+		t.Fatalf(`An error occurred while throttling the request.. %v`, err)
+	}
+
+	if n := hook.n - before; n != 1 {
+		t.Fatalf(`Throttle is supposed to make a single round trip.. It made %d`, n)
+	}
+
+	gottletest.AssertLimitedAfter(t, throttler, r, 3)
+}
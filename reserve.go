@@ -0,0 +1,187 @@
+package gottle
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+//ErrInvalidReservation is returned when a reservation asks for fewer
+//than one hit, or more than the limit of the key allows
+var ErrInvalidReservation = errors.New(
+	`gottle: A reservation must be for at least one hit and no more than the limit`)
+
+//Reservation holds hits recorded for a key ahead of the work they are for
+type Reservation struct {
+	t     *OnecacheThrottler
+	c     client
+	n     int
+	ok    bool
+	delay time.Duration
+
+	//next holds hits recorded under another key for the same work,
+	//which are given back along with these
+	next *Reservation
+}
+
+//OK reports if the hits were recorded, in which case the caller
+//may go ahead right away
+func (r *Reservation) OK() bool {
+	return r.ok
+}
+
+//Delay is how long the caller has to wait before the key can take the
+//hits, at which point it should reserve them again. It is zero for
+//reservations that are OK
+func (r *Reservation) Delay() time.Duration {
+	return r.delay
+}
+
+//Cancel gives the hits of the reservation back, for work that ends up
+//not being done. It is a no-op for reservations that are not OK.
+//Unless the store is a Decrementer, hits recorded for the key by others
+//between reading and writing it back may be lost, as with Restore
+func (r *Reservation) Cancel() error {
+	if !r.ok {
+		return nil
+	}
+
+	r.ok = false
+
+	if r.next != nil {
+		if err := r.next.Cancel(); err != nil {
+			return err
+		}
+	}
+
+	//Shadow throttlers let work through without always recording it
+	if r.n == 0 {
+		return nil
+	}
+
+	if decrementer, ok := r.t.store.(Decrementer); ok {
+		return r.t.timed(r.c, OpDecr, func() error {
+			_, err := decrementer.Decr(r.c.entry(), r.n)
+			return err
+		})
+	}
+
+	item, ok, err := r.t.load(r.c)
+
+	if err != nil || !ok {
+		return err
+	}
+
+	ttl := r.c.resetAt(item.LastThrottledAt).Sub(r.t.now())
+
+	if item.Hits <= r.n || ttl <= 0 {
+		return r.t.timed(r.c, OpDelete, func() error {
+			return r.t.store.Delete(r.c.entry())
+		})
+	}
+
+	return r.t.put(r.c, item.Hits-r.n, item.LastThrottledAt, ttl)
+}
+
+//Reserve records n hits for key if it is not rate limited.
+//Otherwise the reservation says how long until it is no longer.
+//As with ThrottleKey, the key is used as is and the store is what
+//coordinates reservations made by different processes
+func (t *OnecacheThrottler) Reserve(key string, n int) (*Reservation, error) {
+	return t.reserve(context.Background(), key, n)
+}
+
+func (t *OnecacheThrottler) reserve(ctx context.Context, key string, n int) (*Reservation, error) {
+	c := t.clientOfKey(key)
+	c.ctx = ctx
+
+	if n < 1 || n > c.limit {
+		return nil, ErrInvalidReservation
+	}
+
+	r := &Reservation{t: t, c: c, n: n}
+
+	hits, err := t.throttle(c, n)
+
+	t.decided(c, hits, n, err)
+
+	//Shadow throttlers let the work through even though it's hits were
+	//not recorded, so Cancel must not take back anybody else's
+	if t.shadow && err != nil {
+		r.n, err = 0, nil
+	}
+
+	if t.candidate != nil {
+		cc := t.candidate.clientOfKey(candidateKeyPrefix + key)
+		cc.ctx = ctx
+
+		t.candidate.decide(cc, n)
+	}
+
+	if err == nil {
+		r.ok = true
+		return r, nil
+	}
+
+	if err != ErrClientIsRateLimited {
+		return nil, err
+	}
+
+	item, ok, err := t.load(c)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if ok {
+		resetAt := c.resetAt(item.LastThrottledAt)
+
+		//Such stores report the start of their window, which ends
+		//when the store says, not after the throttler's interval
+		if store, isWindowed := t.store.(WindowedStore); isWindowed {
+			resetAt = item.LastThrottledAt.Add(store.Window())
+		}
+
+		r.delay = resetAt.Sub(t.now())
+	}
+
+	if r.delay < 0 {
+		r.delay = 0
+	}
+
+	return r, nil
+}
+
+//minWait is how long Wait sleeps at the very least,
+//so a key whose reset time has just passed isn't retried in a loop
+const minWait = time.Millisecond * 10
+
+//Wait blocks until key can take a hit and records it, or ctx is done.
+//Rate limited callers sleep until the limit resets and try again,
+//so callers in other processes sharing the store wait their turn too
+func (t *OnecacheThrottler) Wait(ctx context.Context, key string) error {
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		r, err := t.reserve(ctx, key, defaultThrottledItemIncrement)
+
+		if err != nil {
+			return err
+		}
+
+		if r.OK() {
+			return nil
+		}
+
+		timer := time.NewTimer(max(r.Delay(), minWait))
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
@@ -0,0 +1,225 @@
+package gottle
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/adelowo/gottle/sharded"
+	"github.com/adelowo/onecache"
+	"github.com/adelowo/onecache/memory"
+)
+
+func TestOnecacheThrottler_Reserve(t *testing.T) {
+	clock := &fixedClock{time.Now()}
+
+	throttler := NewOneCacheThrottler(
+		Clock(clock), ThrottleCondition(time.Minute, 5))
+
+	r, err := throttler.Reserve("batch", 5)
+
+	if err != nil {
+		t.Fatalf(`An error occurred while reserving.. %v`, err)
+	}
+
+	if !r.OK() || r.Delay() != 0 {
+		t.Fatalf(`Expected the reservation to be OK right away.. Got %v after %v`, r.OK(), r.Delay())
+	}
+
+	clock.t = clock.t.Add(time.Second * 20)
+
+	r, err = throttler.Reserve("batch", 1)
+
+	if err != nil {
+		t.Fatalf(`An error occurred while reserving.. %v`, err)
+	}
+
+	if r.OK() {
+		t.Fatal(`Expected the reservation to be delayed`)
+	}
+
+	if r.Delay() != time.Second*40 {
+		t.Fatalf(`Delays differ.. Expected %v.. Got %v`, time.Second*40, r.Delay())
+	}
+
+	//Cancelling a reservation that holds no hits gives nothing back
+	if err := r.Cancel(); err != nil {
+		t.Fatalf(`An error occurred while cancelling.. %v`, err)
+	}
+
+	if status, _ := throttler.Status("batch"); status.Hits != 5 {
+		t.Fatalf(`Hits differ.. Expected %d.. Got %d`, 5, status.Hits)
+	}
+}
+
+func TestOnecacheThrottler_Reserve_invalid(t *testing.T) {
+	throttler := NewOneCacheThrottler(ThrottleCondition(time.Minute, 5))
+
+	for _, n := range []int{0, 6} {
+		if _, err := throttler.Reserve("batch", n); err != ErrInvalidReservation {
+			t.Fatalf(`Errors differ.. Expected %v.. Got %v`, ErrInvalidReservation, err)
+		}
+	}
+}
+
+func TestReservation_Cancel(t *testing.T) {
+	stores := map[string]func() onecache.Store{
+		"gob":     func() onecache.Store { return memory.New() },
+		"counter": func() onecache.Store { return sharded.New() },
+	}
+
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			throttler := NewOneCacheThrottler(
+				Store(store()), ThrottleCondition(time.Minute, 5))
+
+			throttler.Reserve("batch", 2)
+
+			r, _ := throttler.Reserve("batch", 3)
+
+			if err := r.Cancel(); err != nil {
+				t.Fatalf(`An error occurred while cancelling.. %v`, err)
+			}
+
+			status, _ := throttler.Status("batch")
+
+			if status.Hits != 2 {
+				t.Fatalf(`Hits differ.. Expected %d.. Got %d`, 2, status.Hits)
+			}
+
+			//The hits that are left still expire when they would have
+			if resetIn := time.Until(status.ResetAt); resetIn > time.Minute || resetIn < time.Second*55 {
+				t.Fatalf(`Expected the key to reset in a minute.. Got %v`, resetIn)
+			}
+
+			//A reservation is only given back once
+			r.Cancel()
+
+			if status, _ := throttler.Status("batch"); status.Hits != 2 {
+				t.Fatalf(`Hits differ.. Expected %d.. Got %d`, 2, status.Hits)
+			}
+		})
+	}
+}
+
+func TestReservation_Cancel_shadow(t *testing.T) {
+	throttler := NewOneCacheThrottler(
+		Store(sharded.New()), ThrottleCondition(time.Minute, 5), Shadow(true))
+
+	throttler.Reserve("batch", 4)
+
+	//The would-be denial is let through without it's hits being recorded
+	r, _ := throttler.Reserve("batch", 3)
+
+	if !r.OK() {
+		t.Fatal(`Shadow throttlers are not supposed to deny reservations`)
+	}
+
+	if err := r.Cancel(); err != nil {
+		t.Fatalf(`An error occurred while cancelling.. %v`, err)
+	}
+
+	if status, _ := throttler.Status("batch"); status.Hits != 4 {
+		t.Fatalf(`Hits differ.. Expected %d.. Got %d`, 4, status.Hits)
+	}
+}
+
+//windowStore counts hits in a single window of it's own starting at start,
+//reporting the start of the window as the most recent hit like memcached does
+type windowStore struct {
+	onecache.Store
+	start  time.Time
+	window time.Duration
+	hits   atomic.Int64
+	calls  atomic.Int64
+}
+
+func (s *windowStore) Window() time.Duration {
+	return s.window
+}
+
+func (s *windowStore) Incr(key string, n int, now time.Time, ttl time.Duration) (int, error) {
+	return int(s.hits.Add(int64(n))), nil
+}
+
+func (s *windowStore) IncrUnder(key string, n, limit int, now time.Time, ttl time.Duration) (int, bool, error) {
+	s.calls.Add(1)
+
+	if hits := int(s.hits.Load()); hits+n > limit {
+		return hits, false, nil
+	}
+
+	return int(s.hits.Add(int64(n))), true, nil
+}
+
+func (s *windowStore) Count(key string) (int, time.Time, error) {
+	return int(s.hits.Load()), s.start, nil
+}
+
+func TestOnecacheThrottler_Reserve_windowedStore(t *testing.T) {
+	store := &windowStore{Store: memory.New(), start: time.Now(), window: time.Minute}
+
+	throttler := NewOneCacheThrottler(
+		Store(store), ThrottleCondition(time.Second, 1))
+
+	throttler.Reserve("batch", 1)
+
+	r, err := throttler.Reserve("batch", 1)
+
+	if err != nil {
+		t.Fatalf(`An error occurred while reserving.. %v`, err)
+	}
+
+	//The store's window ends a minute after it started, not a second
+	if r.Delay() < time.Second*50 {
+		t.Fatalf(`Expected the reservation to be delayed until the store's window ends.. Got %v`, r.Delay())
+	}
+}
+
+func TestOnecacheThrottler_Wait_resetPassed(t *testing.T) {
+	//A window whose end has just passed but still holds the hits
+	store := &windowStore{Store: memory.New(), start: time.Now().Add(-time.Minute), window: time.Minute}
+
+	throttler := NewOneCacheThrottler(
+		Store(store), ThrottleCondition(time.Minute, 1))
+
+	throttler.Reserve("batch", 1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*50)
+	defer cancel()
+
+	if err := throttler.Wait(ctx, "batch"); err != context.DeadlineExceeded {
+		t.Fatalf(`Errors differ.. Expected %v.. Got %v`, context.DeadlineExceeded, err)
+	}
+
+	if calls := store.calls.Load(); calls > 10 {
+		t.Fatalf(`Expected Wait to sleep between attempts.. Tried %d times`, calls)
+	}
+}
+
+func TestOnecacheThrottler_Wait(t *testing.T) {
+	throttler := NewOneCacheThrottler(
+		ThrottleCondition(time.Millisecond*100, 1))
+
+	if err := throttler.Wait(context.Background(), "batch"); err != nil {
+		t.Fatalf(`An error occurred while waiting.. %v`, err)
+	}
+
+	start := time.Now()
+
+	if err := throttler.Wait(context.Background(), "batch"); err != nil {
+		t.Fatalf(`An error occurred while waiting.. %v`, err)
+	}
+
+	if waited := time.Since(start); waited < time.Millisecond*50 {
+		t.Fatalf(`Expected to have waited for the limit to reset.. Waited %v`, waited)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*10)
+	defer cancel()
+
+	if err := throttler.Wait(ctx, "batch"); err != context.DeadlineExceeded {
+		t.Fatalf(`Errors differ.. Expected %v.. Got %v`, context.DeadlineExceeded, err)
+	}
+}
@@ -0,0 +1,62 @@
+package gottle
+
+const (
+	candidateSuffix    = "-candidate"
+	candidateKeyPrefix = "candidate:"
+)
+
+//newCandidate returns a shadow throttler for the Candidate option.
+//It shares the store, IP provider, clock, metrics, tracer, logger and
+//hooks of t, as well as it's limits and quota, before opts are applied.
+//It is named after t with a "-candidate" suffix and it's keys, including
+//those of a KeyGenerator in opts, are prefixed with "candidate:" so it
+//does not count against the hits of t.
+//Whatever opts set, the candidate is always in shadow mode
+func newCandidate(t *OnecacheThrottler, opts []Option) *OnecacheThrottler {
+	maxRequests, interval := t.throttleCondition()
+
+	candidate := &OnecacheThrottler{
+		ipProvider:   t.ipProvider,
+		store:        t.store,
+		keyGenerator: t.keyGenerator,
+		clock:        t.clock,
+		metrics:      t.metrics,
+		tracer:       t.tracer,
+		hooks:        t.registry(),
+		name:         t.name + candidateSuffix,
+		maxRequests:  maxRequests,
+		interval:     interval,
+		quota:        t.quota,
+	}
+
+	//The candidate gets it's own logger so options like LoggingLevels
+	//don't change how t logs
+	if t.logger != nil {
+		candidate.logger = &logger{
+			l:        t.logger.l,
+			levels:   t.logger.levels,
+			sampling: t.logger.sampling,
+		}
+	}
+
+	for _, opt := range opts {
+		opt(candidate)
+	}
+
+	//Prefixed after opts, which may set a key generator of their own
+	keyGenerator := candidate.keyGenerator
+
+	candidate.keyGenerator = func(ip string) string {
+		return candidateKeyPrefix + keyGenerator(ip)
+	}
+
+	candidate.shadow = true
+
+	//A candidate of a candidate has nothing to be compared with
+	candidate.candidate = nil
+	candidate.candidateOpts = nil
+
+	setDefaultsForEmptyFields(candidate)
+
+	return candidate
+}
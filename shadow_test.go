@@ -0,0 +1,179 @@
+package gottle
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/adelowo/onecache/memory"
+)
+
+func TestOnecacheThrottler_Shadow(t *testing.T) {
+	r, teardown, err := setUp(t)
+	defer teardown()
+
+	if err != nil {
+		t.Fatalf("An error occurred while setting up the test ..%v", err)
+	}
+
+	r.Header.Set(xForwardedFor, "123.456.789.000")
+
+	recorder := newFakeRecorder()
+
+	throttler := NewOneCacheThrottler(
+		Name("login"),
+		Shadow(true),
+		Metrics(recorder),
+		ThrottleCondition(time.Minute, 2))
+
+	var limited []Event
+
+	throttler.OnLimited(func(e Event) { limited = append(limited, e) })
+
+	for i := 0; i < 4; i++ {
+		if err := throttler.Throttle(r); err != nil {
+			t.Fatalf(`A throttler in shadow mode is not supposed to reject requests.. Got %v`, err)
+		}
+	}
+
+	if throttler.IsRateLimited(r) {
+		t.Fatal(`A throttler in shadow mode is not supposed to report clients as limited`)
+	}
+
+	if len(limited) != 1 || !limited[0].Shadow {
+		t.Fatalf(`Expected a single shadow limited event.. Got %+v`, limited)
+	}
+
+	expected := []recordedDecision{
+		{"login", true, true}, {"login", true, true},
+		{"login", false, true}, {"login", false, true},
+	}
+
+	if len(recorder.decisions) != len(expected) {
+		t.Fatalf(`Decisions differ.. Expected %v.. Got %v`, expected, recorder.decisions)
+	}
+
+	for i, v := range expected {
+		if recorder.decisions[i] != v {
+			t.Fatalf(`Decisions differ.. Expected %v.. Got %v`, expected, recorder.decisions)
+		}
+	}
+
+	//The would-be state is still available
+	if status, _ := throttler.Status("123.456.789.000"); !status.Limited {
+		t.Fatalf(`Expected the status to report the client as limited.. Got %+v`, status)
+	}
+}
+
+func TestOnecacheThrottler_Shadow_storeError(t *testing.T) {
+	r, teardown, err := setUp(t)
+	defer teardown()
+
+	if err != nil {
+		t.Fatalf("An error occurred while setting up the test ..%v", err)
+	}
+
+	r.Header.Set(xForwardedFor, "123.456.789.000")
+
+	throttler := NewOneCacheThrottler(
+		Shadow(true),
+		Store(&failingStore{memory.New(), errors.New("oops")}))
+
+	var storeErrors int
+
+	throttler.OnStoreError(func(StoreErrorEvent) { storeErrors++ })
+
+	if err := throttler.Throttle(r); err != nil {
+		t.Fatalf(`A throttler in shadow mode is not supposed to fail requests.. Got %v`, err)
+	}
+
+	if storeErrors != 1 {
+		t.Fatalf(`Expected the store error to be reported.. Got %d`, storeErrors)
+	}
+}
+
+func TestOnecacheThrottler_Candidate(t *testing.T) {
+	r, teardown, err := setUp(t)
+	defer teardown()
+
+	if err != nil {
+		t.Fatalf("An error occurred while setting up the test ..%v", err)
+	}
+
+	r.Header.Set(xForwardedFor, "123.456.789.000")
+
+	recorder := newFakeRecorder()
+
+	throttler := NewOneCacheThrottler(
+		Name("login"),
+		Metrics(recorder),
+		ThrottleCondition(time.Minute, 3),
+		Candidate(ThrottleCondition(time.Minute, 1)))
+
+	var limited []Event
+
+	throttler.OnLimited(func(e Event) { limited = append(limited, e) })
+
+	for i := 0; i < 3; i++ {
+		if err := throttler.Throttle(r); err != nil {
+			t.Fatalf(`The enforcing policy is not supposed to reject request %d.. Got %v`, i+1, err)
+		}
+	}
+
+	if err := throttler.Throttle(r); err != ErrClientIsRateLimited {
+		t.Fatalf(`Errors differ.. Expected %v.. Got %v`, ErrClientIsRateLimited, err)
+	}
+
+	if len(limited) != 2 {
+		t.Fatalf(`Expected a limited event for each policy.. Got %+v`, limited)
+	}
+
+	candidate, enforcing := limited[0], limited[1]
+
+	if candidate.Name != "login-candidate" || !candidate.Shadow ||
+		candidate.Key != "candidate:123.456.789.000" || candidate.Hits != 1 {
+		t.Fatalf(`The candidate's event was not filled in properly.. Got %+v`, candidate)
+	}
+
+	if enforcing.Name != "login" || enforcing.Shadow || enforcing.Hits != 3 {
+		t.Fatalf(`The enforcing policy's event was not filled in properly.. Got %+v`, enforcing)
+	}
+
+	var shadowDenied int
+
+	for _, d := range recorder.decisions {
+		if d.name == "login-candidate" && d.shadow && !d.allowed {
+			shadowDenied++
+		}
+	}
+
+	if shadowDenied != 3 {
+		t.Fatalf(`Expected the candidate to have denied %d requests.. Got %d`, 3, shadowDenied)
+	}
+}
+
+func TestOnecacheThrottler_Candidate_keyGenerator(t *testing.T) {
+	r, teardown, err := setUp(t)
+	defer teardown()
+
+	if err != nil {
+		t.Fatalf("An error occurred while setting up the test ..%v", err)
+	}
+
+	r.Header.Set(xForwardedFor, "123.456.789.000")
+
+	throttler := NewOneCacheThrottler(
+		ThrottleCondition(time.Minute, 3),
+		Candidate(KeyGenerator(func(ip string) string { return "user:" + ip })))
+
+	throttler.Throttle(r)
+
+	//The candidate's hits are kept apart from the enforcing policy's
+	if hits, _ := throttler.Attempts(r); hits != 1 {
+		t.Fatalf(`Hits differ.. Expected %d.. Got %d`, 1, hits)
+	}
+
+	if !throttler.store.Has("candidate:user:123.456.789.000") {
+		t.Fatal(`Expected the candidate's key to be prefixed`)
+	}
+}
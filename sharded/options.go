@@ -0,0 +1,23 @@
+package sharded
+
+import "time"
+
+//Option configures a Store
+type Option func(*Store)
+
+//Shards is an Option that sets the number of shards keys are spread across.
+//More shards means less contention on hot paths at the cost of memory
+func Shards(n int) Option {
+	return func(s *Store) {
+		s.numShards = n
+	}
+}
+
+//SweepInterval is an Option that sets how often expired entries are removed.
+//A zero or negative interval disables the background sweeper,
+//in which case GC has to be called manually
+func SweepInterval(interval time.Duration) Option {
+	return func(s *Store) {
+		s.sweepInterval = interval
+	}
+}
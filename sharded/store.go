@@ -0,0 +1,468 @@
+//Package sharded provides an in-process store built for rate limiting.
+//
+//Counters are kept natively in sharded maps and updated with atomic
+//compare-and-swap, so hot keys never go through gob encoding or a
+//global lock. It implements both onecache.Store and gottle.Counter
+//(as well as gottle.LimitCounter and gottle.Decrementer) and is meant
+//for single instance services with a high request rate
+package sharded
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/adelowo/onecache"
+)
+
+const (
+	defaultShards        = 64
+	defaultSweepInterval = time.Minute
+)
+
+//state is an immutable snapshot of an entry.
+//Entries are only ever updated by swapping in a new state
+type state struct {
+	hits    int
+	last    int64 //unix nano of the most recent hit
+	expires int64 //unix nano after which the entry is dead
+	data    []byte
+}
+
+func (s *state) expired(now int64) bool {
+	return now > s.expires
+}
+
+//tombstone marks an entry that has been removed from it's shard.
+//Writers that find it have to start over with a fresh entry
+var tombstone = &state{}
+
+type entry struct {
+	state atomic.Pointer[state]
+}
+
+type shard struct {
+	entries sync.Map
+}
+
+func (s *shard) entry(key string) *entry {
+	if e, ok := s.entries.Load(key); ok {
+		return e.(*entry)
+	}
+
+	e, _ := s.entries.LoadOrStore(key, new(entry))
+	return e.(*entry)
+}
+
+//remove kills e if it still holds old, then drops it from the shard
+func (s *shard) remove(key string, e *entry, old *state) bool {
+	if !e.state.CompareAndSwap(old, tombstone) {
+		return false
+	}
+
+	s.entries.CompareAndDelete(key, e)
+	return true
+}
+
+//Store is a sharded in-memory store
+type Store struct {
+	shards        []*shard
+	numShards     int
+	sweepInterval time.Duration
+
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+//New returns a Store and starts it's background sweeper.
+//Call Close to stop the sweeper once the store is no longer needed
+func New(opts ...Option) *Store {
+	s := &Store{
+		numShards:     defaultShards,
+		sweepInterval: defaultSweepInterval,
+		stop:          make(chan struct{}),
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	if s.numShards < 1 {
+		s.numShards = 1
+	}
+
+	s.shards = make([]*shard, s.numShards)
+
+	for i := range s.shards {
+		s.shards[i] = new(shard)
+	}
+
+	if s.sweepInterval > 0 {
+		go s.sweep()
+	}
+
+	return s
+}
+
+func (s *Store) shard(key string) *shard {
+	//Inlined fnv-1a so picking a shard doesn't allocate
+	h := uint32(2166136261)
+
+	for i := 0; i < len(key); i++ {
+		h ^= uint32(key[i])
+		h *= 16777619
+	}
+
+	return s.shards[h%uint32(len(s.shards))]
+}
+
+func (s *Store) sweep() {
+	ticker := time.NewTicker(s.sweepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.GC()
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+//GC removes every expired entry.
+//It is called periodically by the sweeper but can also be called directly
+func (s *Store) GC() {
+	now := time.Now().UnixNano()
+
+	for _, sh := range s.shards {
+		sh.entries.Range(func(k, v interface{}) bool {
+			e := v.(*entry)
+
+			if old := e.state.Load(); old != nil && old != tombstone && old.expired(now) {
+				sh.remove(k.(string), e, old)
+			}
+
+			return true
+		})
+	}
+}
+
+//Close stops the background sweeper
+func (s *Store) Close() error {
+	s.stopOnce.Do(func() {
+		close(s.stop)
+	})
+
+	return nil
+}
+
+//Incr adds n hits to key.
+//The key expires ttl after the last time it was incremented
+func (s *Store) Incr(key string, n int, now time.Time, ttl time.Duration) (int, error) {
+	sh := s.shard(key)
+
+	for {
+		e := sh.entry(key)
+		old := e.state.Load()
+
+		if old == tombstone {
+			continue
+		}
+
+		wall := time.Now().UnixNano()
+
+		next := &state{
+			hits:    n,
+			last:    now.UnixNano(),
+			expires: wall + int64(ttl),
+		}
+
+		//Byte values set through Set are overwritten
+		if old != nil && old.data == nil && !old.expired(wall) {
+			next.hits += old.hits
+		}
+
+		if e.state.CompareAndSwap(old, next) {
+			return next.hits, nil
+		}
+	}
+}
+
+//IncrUnder adds n hits to key unless they would take it past limit
+//with it's most recent hit no older than ttl
+func (s *Store) IncrUnder(key string, n, limit int, now time.Time, ttl time.Duration) (int, bool, error) {
+	sh := s.shard(key)
+
+	for {
+		e := sh.entry(key)
+		old := e.state.Load()
+
+		if old == tombstone {
+			continue
+		}
+
+		wall := time.Now().UnixNano()
+
+		live := old != nil && old.data == nil && !old.expired(wall)
+
+		var hits int
+
+		if live {
+			hits = old.hits
+		}
+
+		if n > limit || live && hits+n > limit && now.UnixNano()-old.last <= int64(ttl) {
+			return hits, false, nil
+		}
+
+		next := &state{
+			hits:    hits + n,
+			last:    now.UnixNano(),
+			expires: wall + int64(ttl),
+		}
+
+		if e.state.CompareAndSwap(old, next) {
+			return next.hits, true, nil
+		}
+	}
+}
+
+//Decr takes n hits back from key, leaving it's most recent hit and
+//expiry as they are, and returns the hits left. Keys that don't exist
+//are left alone
+func (s *Store) Decr(key string, n int) (int, error) {
+	v, ok := s.shard(key).entries.Load(key)
+
+	if !ok {
+		return 0, nil
+	}
+
+	e := v.(*entry)
+
+	for {
+		old := e.state.Load()
+
+		if old == nil || old == tombstone || old.data != nil || old.expired(time.Now().UnixNano()) {
+			return 0, nil
+		}
+
+		next := *old
+		next.hits -= n
+
+		if next.hits < 0 {
+			next.hits = 0
+		}
+
+		if e.state.CompareAndSwap(old, &next) {
+			return next.hits, nil
+		}
+	}
+}
+
+//Count returns the hits and most recent hit recorded for key
+func (s *Store) Count(key string) (int, time.Time, error) {
+	st, ok := s.load(key)
+
+	if !ok || st.data != nil {
+		return 0, time.Time{}, onecache.ErrCacheMiss
+	}
+
+	return st.hits, time.Unix(0, st.last), nil
+}
+
+func (s *Store) load(key string) (*state, bool) {
+	e, ok := s.shard(key).entries.Load(key)
+
+	if !ok {
+		return nil, false
+	}
+
+	st := e.(*entry).state.Load()
+
+	if st == nil || st == tombstone || st.expired(time.Now().UnixNano()) {
+		return nil, false
+	}
+
+	return st, true
+}
+
+//Entry is a copy of the state held for a key
+type Entry struct {
+	//Counter is true for keys holding hits rather than data
+	Counter         bool
+	Hits            int
+	LastThrottledAt time.Time
+	Data            []byte
+	ExpiresAt       time.Time
+}
+
+//Range calls fn with a copy of every key that has not expired,
+//stopping early if fn returns false
+func (s *Store) Range(fn func(key string, e Entry) bool) {
+	now := time.Now().UnixNano()
+
+	for _, sh := range s.shards {
+		cont := true
+
+		sh.entries.Range(func(k, v interface{}) bool {
+			st := v.(*entry).state.Load()
+
+			if st == nil || st == tombstone || st.expired(now) {
+				return true
+			}
+
+			e := Entry{
+				Counter:   st.data == nil,
+				Data:      st.data,
+				ExpiresAt: time.Unix(0, st.expires),
+			}
+
+			if e.Counter {
+				e.Hits = st.hits
+				e.LastThrottledAt = time.Unix(0, st.last)
+			}
+
+			cont = fn(k.(string), e)
+			return cont
+		})
+
+		if !cont {
+			return
+		}
+	}
+}
+
+//Enumerate calls fn for every key starting with prefix that has not expired
+func (s *Store) Enumerate(prefix string, fn func(key string) bool) error {
+	s.Range(func(key string, e Entry) bool {
+		if !strings.HasPrefix(key, prefix) {
+			return true
+		}
+
+		return fn(key)
+	})
+
+	return nil
+}
+
+//Restore puts e back under key, replacing whatever is there.
+//Entries that have already expired are skipped
+func (s *Store) Restore(key string, e Entry) {
+	if time.Now().After(e.ExpiresAt) {
+		return
+	}
+
+	next := &state{expires: e.ExpiresAt.UnixNano()}
+
+	if e.Counter {
+		next.hits = e.Hits
+		next.last = e.LastThrottledAt.UnixNano()
+	} else {
+		next.data = e.Data
+
+		if next.data == nil {
+			next.data = []byte{}
+		}
+	}
+
+	sh := s.shard(key)
+
+	for {
+		ent := sh.entry(key)
+		old := ent.state.Load()
+
+		if old == tombstone {
+			continue
+		}
+
+		if ent.state.CompareAndSwap(old, next) {
+			return
+		}
+	}
+}
+
+//Set stores data under key
+func (s *Store) Set(key string, data []byte, expires time.Duration) error {
+	sh := s.shard(key)
+
+	//A nil data is how counters are told apart
+	if data == nil {
+		data = []byte{}
+	}
+
+	next := &state{
+		data:    data,
+		expires: time.Now().Add(expires).UnixNano(),
+	}
+
+	for {
+		e := sh.entry(key)
+		old := e.state.Load()
+
+		if old == tombstone {
+			continue
+		}
+
+		if e.state.CompareAndSwap(old, next) {
+			return nil
+		}
+	}
+}
+
+//Get fetches the data stored under key.
+//For counters, the number of hits is returned as a decimal string
+func (s *Store) Get(key string) ([]byte, error) {
+	st, ok := s.load(key)
+
+	if !ok {
+		return nil, onecache.ErrCacheMiss
+	}
+
+	if st.data == nil {
+		return []byte(strconv.Itoa(st.hits)), nil
+	}
+
+	return st.data, nil
+}
+
+//Has checks if key exists and has not expired
+func (s *Store) Has(key string) bool {
+	_, ok := s.load(key)
+	return ok
+}
+
+//Delete removes key
+func (s *Store) Delete(key string) error {
+	sh := s.shard(key)
+
+	v, ok := sh.entries.Load(key)
+
+	if !ok {
+		return nil
+	}
+
+	e := v.(*entry)
+
+	for {
+		old := e.state.Load()
+
+		if old == tombstone || sh.remove(key, e, old) {
+			return nil
+		}
+	}
+}
+
+//Flush removes every key
+func (s *Store) Flush() error {
+	for _, sh := range s.shards {
+		sh.entries.Range(func(k, _ interface{}) bool {
+			s.Delete(k.(string))
+			return true
+		})
+	}
+
+	return nil
+}
@@ -0,0 +1,363 @@
+package sharded
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/adelowo/gottle"
+	"github.com/adelowo/gottle/gottletest"
+	"github.com/adelowo/onecache"
+	"github.com/adelowo/onecache/memory"
+)
+
+var _ onecache.Store = &Store{}
+var _ onecache.GarbageCollector = &Store{}
+var _ gottle.Counter = &Store{}
+var _ gottle.LimitCounter = &Store{}
+var _ gottle.Decrementer = &Store{}
+var _ gottle.Enumerator = &Store{}
+
+func TestStore_SetGet(t *testing.T) {
+	store := New(SweepInterval(0))
+	defer store.Close()
+
+	if err := store.Set("key", []byte("value"), time.Minute); err != nil {
+		t.Fatalf(`An error occurred while setting the key.. %v`, err)
+	}
+
+	buf, err := store.Get("key")
+
+	if err != nil {
+		t.Fatalf(`An error occurred while fetching the key.. %v`, err)
+	}
+
+	if string(buf) != "value" {
+		t.Fatalf(`Data differs.. Expected %s.. Got %s`, "value", buf)
+	}
+
+	if err := store.Delete("key"); err != nil {
+		t.Fatalf(`An error occurred while deleting the key.. %v`, err)
+	}
+
+	if store.Has("key") {
+		t.Fatal(`The key is not supposed to exist after it has been deleted`)
+	}
+
+	if _, err := store.Get("key"); err != onecache.ErrCacheMiss {
+		t.Fatalf(`Errors differ.. Expected %v.. Got %v`, onecache.ErrCacheMiss, err)
+	}
+}
+
+func TestStore_Incr(t *testing.T) {
+	store := New(SweepInterval(0))
+	defer store.Close()
+
+	now := time.Now()
+
+	for i := 1; i <= 3; i++ {
+		hits, err := store.Incr("key", 2, now, time.Minute)
+
+		if err != nil {
+			t.Fatalf(`An error occurred while incrementing the key.. %v`, err)
+		}
+
+		if hits != i*2 {
+			t.Fatalf(`Hits differ.. Expected %d.. Got %d`, i*2, hits)
+		}
+	}
+
+	hits, last, err := store.Count("key")
+
+	if err != nil {
+		t.Fatalf(`An error occurred while counting the key.. %v`, err)
+	}
+
+	if hits != 6 || !last.Equal(now) {
+		t.Fatalf(`Count differs.. Expected (%d, %v).. Got (%d, %v)`, 6, now, hits, last)
+	}
+
+	buf, err := store.Get("key")
+
+	if err != nil || string(buf) != "6" {
+		t.Fatalf(`Expected the hits as a string.. Got %s, %v`, buf, err)
+	}
+}
+
+func TestStore_IncrUnder(t *testing.T) {
+	store := New(SweepInterval(0))
+	defer store.Close()
+
+	now := time.Now()
+
+	for i := 1; i <= 3; i++ {
+		hits, added, err := store.IncrUnder("key", 1, 3, now, time.Minute)
+
+		if err != nil || !added || hits != i {
+			t.Fatalf(`Expected hit %d to be added.. Got %d, %v, %v`, i, hits, added, err)
+		}
+	}
+
+	hits, added, err := store.IncrUnder("key", 1, 3, now, time.Minute)
+
+	if err != nil || added || hits != 3 {
+		t.Fatalf(`Expected the limit to be enforced.. Got %d, %v, %v`, hits, added, err)
+	}
+
+	//Once the last hit is older than the interval, hits can be added again
+	hits, added, _ = store.IncrUnder("key", 1, 3, now.Add(time.Minute*2), time.Minute)
+
+	if !added || hits != 4 {
+		t.Fatalf(`Expected the hit to be added.. Got %d, %v`, hits, added)
+	}
+}
+
+func TestStore_IncrUnder_cost(t *testing.T) {
+	store := New(SweepInterval(0))
+	defer store.Close()
+
+	now := time.Now()
+
+	if hits, added, err := store.IncrUnder("key", 7, 10, now, time.Minute); err != nil || !added || hits != 7 {
+		t.Fatalf(`Expected the hits to be added.. Got %d, %v, %v`, hits, added, err)
+	}
+
+	//Hits that would go past the limit are not added at all
+	if hits, added, err := store.IncrUnder("key", 4, 10, now, time.Minute); err != nil || added || hits != 7 {
+		t.Fatalf(`Expected the limit to be enforced.. Got %d, %v, %v`, hits, added, err)
+	}
+
+	if hits, added, err := store.IncrUnder("key", 3, 10, now, time.Minute); err != nil || !added || hits != 10 {
+		t.Fatalf(`Expected the hits to be added.. Got %d, %v, %v`, hits, added, err)
+	}
+
+	if hits, added, err := store.IncrUnder("other", 11, 10, now, time.Minute); err != nil || added || hits != 0 {
+		t.Fatalf(`Expected more hits than the limit to never be added.. Got %d, %v, %v`, hits, added, err)
+	}
+}
+
+func TestStore_Decr(t *testing.T) {
+	store := New(SweepInterval(0))
+	defer store.Close()
+
+	if hits, err := store.Decr("key", 1); err != nil || hits != 0 {
+		t.Fatalf(`Expected a missing key to be left alone.. Got %d, %v`, hits, err)
+	}
+
+	now := time.Now()
+
+	store.Incr("key", 5, now, time.Minute)
+
+	if hits, err := store.Decr("key", 2); err != nil || hits != 3 {
+		t.Fatalf(`Expected %d hits to be left.. Got %d, %v`, 3, hits, err)
+	}
+
+	if hits, err := store.Decr("key", 5); err != nil || hits != 0 {
+		t.Fatalf(`Expected the hits not to go below zero.. Got %d, %v`, hits, err)
+	}
+
+	if hits, _, err := store.Count("key"); err != nil || hits != 0 {
+		t.Fatalf(`Expected the key to be kept with no hits.. Got %d, %v`, hits, err)
+	}
+}
+
+func TestStore_Incr_expired(t *testing.T) {
+	store := New(SweepInterval(0))
+	defer store.Close()
+
+	store.Incr("key", 5, time.Now(), time.Millisecond)
+
+	time.Sleep(time.Millisecond * 5)
+
+	if _, _, err := store.Count("key"); err != onecache.ErrCacheMiss {
+		t.Fatalf(`Errors differ.. Expected %v.. Got %v`, onecache.ErrCacheMiss, err)
+	}
+
+	hits, _ := store.Incr("key", 1, time.Now(), time.Minute)
+
+	if hits != 1 {
+		t.Fatalf(`An expired counter should start over.. Expected %d.. Got %d`, 1, hits)
+	}
+}
+
+func TestStore_Incr_concurrent(t *testing.T) {
+	store := New(Shards(4), SweepInterval(0))
+	defer store.Close()
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for j := 0; j < 100; j++ {
+				store.Incr("key", 1, time.Now(), time.Minute)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	if hits, _, _ := store.Count("key"); hits != 5000 {
+		t.Fatalf(`Hits were lost.. Expected %d.. Got %d`, 5000, hits)
+	}
+}
+
+func TestStore_GC(t *testing.T) {
+	store := New(SweepInterval(0))
+	defer store.Close()
+
+	store.Incr("short", 1, time.Now(), time.Millisecond)
+	store.Incr("long", 1, time.Now(), time.Minute)
+
+	time.Sleep(time.Millisecond * 5)
+
+	store.GC()
+
+	if _, ok := store.shard("short").entries.Load("short"); ok {
+		t.Fatal(`The expired entry is supposed to have been swept`)
+	}
+
+	if !store.Has("long") {
+		t.Fatal(`The live entry is not supposed to have been swept`)
+	}
+}
+
+func TestStore_RangeRestore(t *testing.T) {
+	store := New(SweepInterval(0))
+	defer store.Close()
+
+	now := time.Now()
+
+	store.Incr("counter", 3, now, time.Minute)
+	store.Set("data", []byte("value"), time.Minute)
+
+	entries := make(map[string]Entry)
+
+	store.Range(func(key string, e Entry) bool {
+		entries[key] = e
+		return true
+	})
+
+	if len(entries) != 2 {
+		t.Fatalf(`Expected %d entries.. Got %d`, 2, len(entries))
+	}
+
+	restored := New(SweepInterval(0))
+	defer restored.Close()
+
+	for key, e := range entries {
+		restored.Restore(key, e)
+	}
+
+	restored.Restore("expired", Entry{Counter: true, Hits: 1, ExpiresAt: now.Add(-time.Second)})
+
+	if hits, last, err := restored.Count("counter"); err != nil || hits != 3 || !last.Equal(now) {
+		t.Fatalf(`Count differs.. Expected (%d, %v).. Got (%d, %v, %v)`, 3, now, hits, last, err)
+	}
+
+	if buf, err := restored.Get("data"); err != nil || string(buf) != "value" {
+		t.Fatalf(`Data differs.. Expected %s.. Got %s, %v`, "value", buf, err)
+	}
+
+	if restored.Has("expired") {
+		t.Fatal(`Expired entries are not supposed to be restored`)
+	}
+}
+
+func TestStore_Enumerate(t *testing.T) {
+	store := New(SweepInterval(0))
+	defer store.Close()
+
+	now := time.Now()
+
+	store.Incr("login-1", 1, now, time.Minute)
+	store.Incr("login-2", 1, now, time.Minute)
+	store.Incr("api-1", 1, now, time.Minute)
+	store.Set("login-expired", []byte("value"), time.Nanosecond)
+
+	time.Sleep(time.Millisecond)
+
+	keys := make(map[string]bool)
+
+	err := store.Enumerate("login-", func(key string) bool {
+		keys[key] = true
+		return true
+	})
+
+	if err != nil {
+		t.Fatalf(`An error occurred while enumerating the keys.. %v`, err)
+	}
+
+	if len(keys) != 2 || !keys["login-1"] || !keys["login-2"] {
+		t.Fatalf(`Keys differ.. Expected %v.. Got %v`, []string{"login-1", "login-2"}, keys)
+	}
+
+	var n int
+
+	store.Enumerate("", func(key string) bool {
+		n++
+		return false
+	})
+
+	if n != 1 {
+		t.Fatalf(`Expected enumeration to stop after %d key.. Got %d`, 1, n)
+	}
+}
+
+func TestStore_withThrottler(t *testing.T) {
+	store := New()
+	defer store.Close()
+
+	r := httptest.NewRequest(http.MethodGet, "/oops", nil)
+	r.Header.Set("X-Forwarded-For", "123.456.789.000")
+
+	throttler := gottle.NewOneCacheThrottler(
+		gottle.Store(store),
+		gottle.ThrottleCondition(time.Minute, 5))
+
+	gottletest.AssertLimitedAfter(t, throttler, r, 5)
+
+	if attempts, err := throttler.Attempts(r); err != nil || attempts != 5 {
+		t.Fatalf(`Attempts differ.. Expected %d.. Got %d, %v`, 5, attempts, err)
+	}
+}
+
+func benchmarkThrottle(b *testing.B, store onecache.Store) {
+	throttler := gottle.NewOneCacheThrottler(
+		gottle.Store(store),
+		gottle.IP(gottle.NewRemoteIP()),
+		gottle.ThrottleCondition(time.Minute, 1<<30))
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	b.RunParallel(func(pb *testing.PB) {
+		r := httptest.NewRequest(http.MethodGet, "/oops", nil)
+
+		var i int
+
+		for pb.Next() {
+			//Spread the load over a thousand clients
+			r.RemoteAddr = "10.0.0." + strconv.Itoa(i%1000) + ":1234"
+			throttler.Throttle(r)
+			i++
+		}
+	})
+}
+
+func BenchmarkThrottle_onecacheMemory(b *testing.B) {
+	benchmarkThrottle(b, memory.New())
+}
+
+func BenchmarkThrottle_sharded(b *testing.B) {
+	store := New()
+	defer store.Close()
+
+	benchmarkThrottle(b, store)
+}
@@ -0,0 +1,84 @@
+package transport
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+//unixThreshold tells reset headers holding a unix timestamp apart from
+//the ones holding a number of seconds, no API resets over 30 years away
+const unixThreshold = 1000000000
+
+//limits is what a response says about the limits upstream
+type limits struct {
+	//remaining is the number of requests upstream still allows
+	remaining int
+
+	//resetAt is when upstream resets it's limit.
+	//It is the zero time if the response doesn't say
+	resetAt time.Time
+}
+
+//limitsOf reads the limits reported by res.
+//ok is false if it reports none
+func limitsOf(res *http.Response, now time.Time) (limits, bool) {
+	retryAt := retryAfter(res.Header.Get("Retry-After"), now)
+
+	switch res.StatusCode {
+	case http.StatusTooManyRequests:
+		return limits{resetAt: retryAt}, true
+
+	case http.StatusServiceUnavailable:
+		//Only a Retry-After tells an overloaded upstream from a broken one
+		if !retryAt.IsZero() {
+			return limits{resetAt: retryAt}, true
+		}
+	}
+
+	remaining, err := strconv.Atoi(header(res.Header, "Remaining"))
+
+	if err != nil || remaining < 0 {
+		return limits{}, false
+	}
+
+	l := limits{remaining: remaining}
+
+	if reset, err := strconv.ParseInt(header(res.Header, "Reset"), 10, 64); err == nil && reset >= 0 {
+		if reset >= unixThreshold {
+			l.resetAt = time.Unix(reset, 0)
+		} else {
+			l.resetAt = now.Add(time.Duration(reset) * time.Second)
+		}
+	}
+
+	return l, true
+}
+
+//header returns the RateLimit- header called name,
+//falling back to it's X-RateLimit- counterpart
+func header(h http.Header, name string) string {
+	if v := h.Get("RateLimit-" + name); v != "" {
+		return v
+	}
+
+	return h.Get("X-RateLimit-" + name)
+}
+
+//retryAfter parses a Retry-After header, a number of seconds or an HTTP date.
+//It returns the zero time if the header is missing or invalid
+func retryAfter(v string, now time.Time) time.Time {
+	if v == "" {
+		return time.Time{}
+	}
+
+	if seconds, err := strconv.Atoi(v); err == nil && seconds >= 0 {
+		return now.Add(time.Duration(seconds) * time.Second)
+	}
+
+	if at, err := http.ParseTime(v); err == nil {
+		return at
+	}
+
+	return time.Time{}
+}
@@ -0,0 +1,59 @@
+package transport
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestLimitsOf(t *testing.T) {
+	now := time.Date(2017, time.March, 1, 12, 0, 0, 0, time.UTC)
+
+	tt := []struct {
+		name    string
+		status  int
+		headers map[string]string
+		limits  limits
+		ok      bool
+	}{
+		{"no headers", http.StatusOK, nil, limits{}, false},
+		{"too many requests", http.StatusTooManyRequests,
+			map[string]string{"Retry-After": "30"},
+			limits{resetAt: now.Add(time.Second * 30)}, true},
+		{"retry after a date", http.StatusTooManyRequests,
+			map[string]string{"Retry-After": "Wed, 01 Mar 2017 13:00:00 GMT"},
+			limits{resetAt: now.Add(time.Hour)}, true},
+		{"unavailable", http.StatusServiceUnavailable, nil, limits{}, false},
+		{"unavailable with a delay", http.StatusServiceUnavailable,
+			map[string]string{"Retry-After": "60"},
+			limits{resetAt: now.Add(time.Minute)}, true},
+		{"remaining", http.StatusOK,
+			map[string]string{"RateLimit-Remaining": "7", "RateLimit-Reset": "60"},
+			limits{remaining: 7, resetAt: now.Add(time.Minute)}, true},
+		{"reset as a unix timestamp", http.StatusOK,
+			map[string]string{"X-RateLimit-Remaining": "0", "X-RateLimit-Reset": "1488373200"},
+			limits{resetAt: now.Add(time.Hour)}, true},
+		{"invalid remaining", http.StatusOK,
+			map[string]string{"RateLimit-Remaining": "many"}, limits{}, false},
+	}
+
+	for _, v := range tt {
+		t.Run(v.name, func(t *testing.T) {
+			res := &http.Response{StatusCode: v.status, Header: make(http.Header)}
+
+			for name, value := range v.headers {
+				res.Header.Set(name, value)
+			}
+
+			l, ok := limitsOf(res, now)
+
+			if ok != v.ok {
+				t.Fatalf(`Expected ok to be %v.. Got %v`, v.ok, ok)
+			}
+
+			if l.remaining != v.limits.remaining || !l.resetAt.Equal(v.limits.resetAt) {
+				t.Fatalf(`Limits differ.. Expected %+v.. Got %+v`, v.limits, l)
+			}
+		})
+	}
+}
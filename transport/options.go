@@ -0,0 +1,34 @@
+package transport
+
+import (
+	"net/http"
+	"time"
+)
+
+//Option configures a Transport
+type Option func(*Transport)
+
+//Base is an Option that sets the RoundTripper requests are sent with.
+//It defaults to http.DefaultTransport
+func Base(rt http.RoundTripper) Option {
+	return func(t *Transport) {
+		t.base = rt
+	}
+}
+
+//Key is an Option that sets how requests are grouped.
+//It defaults to Host
+func Key(fn KeyFunc) Option {
+	return func(t *Transport) {
+		t.key = fn
+	}
+}
+
+//Wait is an Option that makes rate limited requests wait up to max for the
+//limit to reset, rather than fail fast. Requests that would have to wait
+//longer, or whose context is done first, fail without waiting it out
+func Wait(max time.Duration) Option {
+	return func(t *Transport) {
+		t.maxWait = max
+	}
+}
@@ -0,0 +1,166 @@
+//Package transport limits the requests an HTTP client sends, for APIs
+//with quotas that ban clients going over them.
+//
+//A Transport wraps another http.RoundTripper and throttles every request,
+//per host by default, before it goes out. Requests over the limit either
+//wait for it to reset or fail fast with gottle.ErrClientIsRateLimited :
+//
+//	client := &http.Client{
+//		Transport: transport.New(throttler, transport.Wait(time.Second*30)),
+//	}
+//
+//The limits upstream reports are honoured too. A 429 Too Many Requests, or a
+//503 Service Unavailable with a Retry-After header, locks the key out until
+//upstream says to retry. RateLimit-Remaining and RateLimit-Reset, or their
+//X-RateLimit- counterparts, use up the local attempts upstream says are gone
+package transport
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/adelowo/gottle"
+)
+
+//minWait is how long a waiting request sleeps at the very least,
+//so a key whose reset time has just passed isn't retried in a loop
+const minWait = time.Millisecond * 10
+
+//Throttler is what a Transport needs from a throttler.
+//gottle.OnecacheThrottler implements it
+type Throttler interface {
+	ThrottleKey(ctx context.Context, key string) error
+	Status(key string) (gottle.KeyStatus, error)
+	Restore(status gottle.KeyStatus) error
+}
+
+//KeyFunc returns the key a request is throttled under
+type KeyFunc func(r *http.Request) string
+
+//Host is a KeyFunc that throttles requests per host they are sent to
+func Host() KeyFunc {
+	return func(r *http.Request) string {
+		return r.URL.Host
+	}
+}
+
+//Transport is an http.RoundTripper that throttles the requests going through it
+type Transport struct {
+	throttler Throttler
+	base      http.RoundTripper
+	key       KeyFunc
+	maxWait   time.Duration
+}
+
+//New returns a Transport that throttles requests through throttler
+//before sending them with http.DefaultTransport
+func New(throttler Throttler, opts ...Option) *Transport {
+	t := &Transport{
+		throttler: throttler,
+		base:      http.DefaultTransport,
+		key:       Host(),
+	}
+
+	for _, opt := range opts {
+		opt(t)
+	}
+
+	return t
+}
+
+//RoundTrip throttles r and sends it.
+//Requests that can't be throttled because of a store error are sent anyway
+func (t *Transport) RoundTrip(r *http.Request) (*http.Response, error) {
+	key := t.key(r)
+
+	if err := t.throttle(r.Context(), key); err != nil {
+		//RoundTrippers must close the body, even when they don't send it
+		if r.Body != nil {
+			r.Body.Close()
+		}
+
+		return nil, err
+	}
+
+	res, err := t.base.RoundTrip(r)
+
+	if err != nil {
+		return nil, err
+	}
+
+	t.observe(key, res)
+
+	return res, nil
+}
+
+//throttle records a hit for key, waiting for the limit to reset
+//if it is rate limited and the wait is no longer than allowed
+func (t *Transport) throttle(ctx context.Context, key string) error {
+	deadline := time.Now().Add(t.maxWait)
+
+	for {
+		if err := t.throttler.ThrottleKey(ctx, key); err != gottle.ErrClientIsRateLimited {
+			return nil
+		}
+
+		delay := minWait
+
+		if status, err := t.throttler.Status(key); err == nil && !status.ResetAt.IsZero() {
+			if d := time.Until(status.ResetAt); d > delay {
+				delay = d
+			}
+		}
+
+		if time.Now().Add(delay).After(deadline) {
+			return gottle.ErrClientIsRateLimited
+		}
+
+		timer := time.NewTimer(delay)
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+//observe adjusts the state of key to the limits res reports
+func (t *Transport) observe(key string, res *http.Response) {
+	limits, ok := limitsOf(res, time.Now())
+
+	if !ok {
+		return
+	}
+
+	status, err := t.throttler.Status(key)
+
+	if err != nil {
+		return
+	}
+
+	hits := status.Limit - limits.remaining
+
+	if hits < 0 {
+		hits = 0
+	}
+
+	//Upstream can only make the local limit stricter
+	if hits < status.Hits {
+		return
+	}
+
+	resetAt := limits.resetAt
+
+	if resetAt.Before(status.ResetAt) {
+		resetAt = status.ResetAt
+	}
+
+	if resetAt.IsZero() || (hits == status.Hits && resetAt.Equal(status.ResetAt)) {
+		return
+	}
+
+	t.throttler.Restore(gottle.KeyStatus{Key: key, Hits: hits, ResetAt: resetAt})
+}
@@ -0,0 +1,224 @@
+package transport
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/adelowo/gottle"
+)
+
+var _ Throttler = &gottle.OnecacheThrottler{}
+var _ http.RoundTripper = &Transport{}
+
+//upstream counts the requests it receives and answers them with respond
+func upstream(t *testing.T, respond func(w http.ResponseWriter)) (*httptest.Server, *int32) {
+	t.Helper()
+
+	var received int32
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&received, 1)
+		respond(w)
+	}))
+
+	t.Cleanup(srv.Close)
+
+	return srv, &received
+}
+
+func ok(w http.ResponseWriter) {
+	w.WriteHeader(http.StatusOK)
+}
+
+func get(client *http.Client, u string) error {
+	res, err := client.Get(u)
+
+	if err != nil {
+		return err
+	}
+
+	res.Body.Close()
+
+	return nil
+}
+
+func TestTransport_failFast(t *testing.T) {
+	srv, received := upstream(t, ok)
+
+	throttler := gottle.NewOneCacheThrottler(
+		gottle.ThrottleCondition(time.Minute, 2))
+
+	client := &http.Client{Transport: New(throttler)}
+
+	for i := 0; i < 2; i++ {
+		if err := get(client, srv.URL); err != nil {
+			t.Fatalf(`Expected request %d to be sent.. Got %v`, i+1, err)
+		}
+	}
+
+	if err := get(client, srv.URL); !errors.Is(err, gottle.ErrClientIsRateLimited) {
+		t.Fatalf(`Errors differ.. Expected %v.. Got %v`, gottle.ErrClientIsRateLimited, err)
+	}
+
+	if n := atomic.LoadInt32(received); n != 2 {
+		t.Fatalf(`Expected upstream to have received %d requests.. Got %d`, 2, n)
+	}
+}
+
+func TestTransport_perHost(t *testing.T) {
+	first, _ := upstream(t, ok)
+	second, _ := upstream(t, ok)
+
+	throttler := gottle.NewOneCacheThrottler(
+		gottle.ThrottleCondition(time.Minute, 1))
+
+	client := &http.Client{Transport: New(throttler)}
+
+	for _, u := range []string{first.URL, second.URL} {
+		if err := get(client, u); err != nil {
+			t.Fatalf(`Expected every host to have it's own limit.. Got %v`, err)
+		}
+	}
+}
+
+func TestTransport_Wait(t *testing.T) {
+	srv, _ := upstream(t, ok)
+
+	t.Run("limit resets in time", func(t *testing.T) {
+		throttler := gottle.NewOneCacheThrottler(
+			gottle.ThrottleCondition(time.Millisecond*100, 1))
+
+		client := &http.Client{Transport: New(throttler, Wait(time.Second))}
+
+		get(client, srv.URL)
+
+		start := time.Now()
+
+		if err := get(client, srv.URL); err != nil {
+			t.Fatalf(`Expected the request to wait for the limit to reset.. Got %v`, err)
+		}
+
+		if waited := time.Since(start); waited < time.Millisecond*50 {
+			t.Fatalf(`Expected the request to have waited.. It took %v`, waited)
+		}
+	})
+
+	t.Run("limit resets too late", func(t *testing.T) {
+		throttler := gottle.NewOneCacheThrottler(
+			gottle.ThrottleCondition(time.Minute, 1))
+
+		client := &http.Client{Transport: New(throttler, Wait(time.Millisecond*50))}
+
+		get(client, srv.URL)
+
+		if err := get(client, srv.URL); !errors.Is(err, gottle.ErrClientIsRateLimited) {
+			t.Fatalf(`Errors differ.. Expected %v.. Got %v`, gottle.ErrClientIsRateLimited, err)
+		}
+	})
+
+	t.Run("context is done", func(t *testing.T) {
+		throttler := gottle.NewOneCacheThrottler(
+			gottle.ThrottleCondition(time.Minute, 1))
+
+		client := &http.Client{Transport: New(throttler, Wait(time.Hour))}
+
+		get(client, srv.URL)
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*50)
+		defer cancel()
+
+		r, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+
+		if _, err := client.Do(r); !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf(`Errors differ.. Expected %v.. Got %v`, context.DeadlineExceeded, err)
+		}
+	})
+}
+
+func TestTransport_upstreamLimits(t *testing.T) {
+	tt := []struct {
+		name    string
+		respond func(w http.ResponseWriter)
+		hits    int
+		resetIn time.Duration
+	}{
+		{"too many requests", func(w http.ResponseWriter) {
+			w.Header().Set("Retry-After", "120")
+			w.WriteHeader(http.StatusTooManyRequests)
+		}, 10, time.Minute * 2},
+		{"too many requests without a delay", func(w http.ResponseWriter) {
+			w.WriteHeader(http.StatusTooManyRequests)
+		}, 10, time.Minute},
+		{"no requests remaining", func(w http.ResponseWriter) {
+			w.Header().Set("RateLimit-Remaining", "0")
+			w.Header().Set("RateLimit-Reset", "300")
+		}, 10, time.Minute * 5},
+		{"some requests remaining", func(w http.ResponseWriter) {
+			w.Header().Set("X-RateLimit-Remaining", "4")
+		}, 6, time.Minute},
+		{"more requests remaining than locally", func(w http.ResponseWriter) {
+			w.Header().Set("RateLimit-Remaining", "100")
+			w.Header().Set("RateLimit-Reset", "3600")
+		}, 1, time.Minute},
+	}
+
+	for _, v := range tt {
+		t.Run(v.name, func(t *testing.T) {
+			srv, _ := upstream(t, v.respond)
+
+			throttler := gottle.NewOneCacheThrottler(
+				gottle.ThrottleCondition(time.Minute, 10))
+
+			get(&http.Client{Transport: New(throttler)}, srv.URL)
+
+			u, _ := url.Parse(srv.URL)
+
+			status, err := throttler.Status(u.Host)
+
+			if err != nil {
+				t.Fatalf(`An error occurred while fetching the status.. %v`, err)
+			}
+
+			if status.Hits != v.hits {
+				t.Fatalf(`Hits differ.. Expected %d.. Got %d`, v.hits, status.Hits)
+			}
+
+			if resetIn := time.Until(status.ResetAt); resetIn > v.resetIn || resetIn < v.resetIn-time.Second*5 {
+				t.Fatalf(`Expected the key to reset in %v.. Got %v`, v.resetIn, resetIn)
+			}
+		})
+	}
+}
+
+//failingThrottler can't reach it's store
+type failingThrottler struct{}
+
+func (failingThrottler) ThrottleKey(ctx context.Context, key string) error {
+	return errors.New("store is down")
+}
+
+func (failingThrottler) Status(key string) (gottle.KeyStatus, error) {
+	return gottle.KeyStatus{}, errors.New("store is down")
+}
+
+func (failingThrottler) Restore(status gottle.KeyStatus) error {
+	return errors.New("store is down")
+}
+
+func TestTransport_storeErrors(t *testing.T) {
+	srv, _ := upstream(t, func(w http.ResponseWriter) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	})
+
+	client := &http.Client{Transport: New(failingThrottler{})}
+
+	if err := get(client, srv.URL); err != nil {
+		t.Fatalf(`Expected the request to be sent.. Got %v`, err)
+	}
+}